@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/batch"
+)
+
+// batchSummary is the combined outcome of a batch, written to --summary and printed with
+// --output json.
+type batchSummary struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+
+	// ErrorCode names the kind of failure of the batch itself. It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// Error reports a batch that could not be planned, in which case no archive is processed.
+	Error string `json:"error,omitempty"`
+
+	Archives  int `json:"archives"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	// Results are the outcomes of the archives, in the order of their input paths.
+	Results []*runResult `json:"results"`
+}
+
+// finish completes the summary with the exit code of the batch.
+func (s *batchSummary) finish(exitCode int) {
+	s.ExitCode = exitCode
+	s.ErrorCode = errorCode(exitCode)
+	s.Status = statusSuccess
+	if exitCode != exitSuccess {
+		s.Status = statusFailure
+	}
+}
+
+// runBatch repackages every archive matching the batch inputs, at most Jobs at a time, and
+// carries on past archives that fail. It records the outcome of every archive in summary and
+// returns the exit code of the batch.
+func runBatch(ctx context.Context, cliOptions *args.Config, logger *slog.Logger, statusOutput io.Writer,
+	summary *batchSummary,
+) int {
+	batchOptions := cliOptions.Batch
+
+	exitCode := processBatch(ctx, cliOptions, logger, statusOutput, summary)
+	summary.finish(exitCode)
+
+	if batchOptions.SummaryPath != "" {
+		if err := writeBatchSummary(batchOptions.SummaryPath, summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Summary Error: %s\n", err)
+			if exitCode == exitSuccess {
+				exitCode = exitIO
+			}
+		}
+	}
+
+	return exitCode
+}
+
+// processBatch plans and processes the archives of a batch, and returns the exit code of the batch.
+func processBatch(ctx context.Context, cliOptions *args.Config, logger *slog.Logger, statusOutput io.Writer,
+	summary *batchSummary,
+) int {
+	batchOptions := cliOptions.Batch
+
+	inputPaths, err := batch.ExpandInputs(batchOptions.Inputs)
+	if err != nil {
+		summary.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "Arguments Error: %s\n", err)
+		return exitUsage
+	}
+
+	jobs, err := batch.PlanJobs(inputPaths, batchOptions.OutputDirectory, batchOptions.OutputTemplate)
+	if err != nil {
+		summary.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "Arguments Error: %s\n", err)
+		return exitUsage
+	}
+
+	summary.Results = batch.Run(ctx, jobs, batchOptions.Jobs, func(ctx context.Context, job batch.Job) *runResult {
+		return processBatchJob(ctx, cliOptions, logger.With("archive", job.InputPath), statusOutput, job)
+	})
+
+	summary.Archives = len(summary.Results)
+	for _, result := range summary.Results {
+		if result.ExitCode == exitSuccess {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	fmt.Fprintf(statusOutput, "Processed %d archives: %d succeeded, %d failed.\n",
+		summary.Archives, summary.Succeeded, summary.Failed)
+
+	switch {
+	case ctx.Err() != nil:
+		return exitCancelled
+	case summary.Failed > 0:
+		return exitBatchFailure
+	default:
+		return exitSuccess
+	}
+}
+
+// processBatchJob processes a single archive of a batch with its own copy of the options, and
+// prints its error prefixed with the archive, as archives are processed concurrently.
+func processBatchJob(ctx context.Context, cliOptions *args.Config, logger *slog.Logger, statusOutput io.Writer,
+	job batch.Job,
+) *runResult {
+	startedAt := time.Now()
+	jobOptions := *cliOptions
+	jobOptions.InputZipPath = job.InputPath
+	jobOptions.OutputZipPath = job.OutputPath
+
+	result := &runResult{Phase: phaseArguments, InputPath: job.InputPath, OutputPath: job.OutputPath}
+	jobExitCode := exitCancelled
+	switch err := args.ValidatePaths(job.InputPath, job.OutputPath); {
+	case ctx.Err() != nil:
+		result.fail(phaseArguments, ctx.Err())
+	case err != nil:
+		result.fail(phaseArguments, err)
+		jobExitCode = exitCode(err)
+	default:
+		jobExitCode = processArchive(ctx, &jobOptions, logger, statusOutput, result, startedAt)
+	}
+
+	result.finish(jobExitCode)
+	if result.Error != "" {
+		printFailure(os.Stderr, job.InputPath+": ", &jobOptions, result)
+	}
+	return result
+}
+
+// writeBatchSummary writes the summary of a batch as indented JSON.
+func writeBatchSummary(summaryPath string, summary *batchSummary) error {
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch summary: %w", err)
+	}
+
+	if err := os.WriteFile(summaryPath, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write batch summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/diff"
+)
+
+// diffOutcome is the machine-readable outcome of the diff command printed with --output json.
+type diffOutcome struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+
+	// ErrorCode names the kind of failure, such as "input_unreadable". It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	OldPath string `json:"old_path,omitempty"`
+	NewPath string `json:"new_path,omitempty"`
+
+	// Result holds the summary and changes, which are only present once the comparison succeeded.
+	*diff.Result
+}
+
+// finish completes the outcome with the exit code of the comparison.
+func (o *diffOutcome) finish(exitCode int) {
+	o.ExitCode = exitCode
+	o.ErrorCode = errorCode(exitCode)
+	o.Status = statusSuccess
+	if exitCode != exitSuccess {
+		o.Status = statusFailure
+	}
+}
+
+// runDiff compares the two archives given after the diff command, records the differences in
+// outcome and returns the exit code. With text output, the differences are printed to output.
+func runDiff(ctx context.Context, arguments []string, output io.Writer, outcome *diffOutcome) int {
+	diffOptions, err := args.ParseDiff(arguments)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Arguments", err)
+	}
+
+	outcome.OldPath = diffOptions.OldZipPath
+	outcome.NewPath = diffOptions.NewZipPath
+
+	result, err := diff.Compare(ctx, diffOptions.OldZipPath, diffOptions.NewZipPath, diff.Options{
+		OldReportPath: diffOptions.OldReportPath,
+		NewReportPath: diffOptions.NewReportPath,
+	})
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Diff", err)
+	}
+
+	outcome.Result = result
+
+	if diffOptions.OutputFormat != args.OutputJSON {
+		printChanges(output, result)
+	}
+	return exitSuccess
+}
+
+// printChanges prints one line per changed file, prefixed with the kind of change, followed by
+// the number of files of each kind.
+func printChanges(writer io.Writer, result *diff.Result) {
+	for _, change := range result.Changes {
+		switch change.Kind {
+		case diff.ChangeAdded:
+			fmt.Fprintf(writer, "A  %s\n", change.New.Name)
+		case diff.ChangeRemoved:
+			fmt.Fprintf(writer, "D  %s\n", change.Old.Name)
+		case diff.ChangeModified:
+			fmt.Fprintf(writer, "M  %s\n", change.New.Name)
+		case diff.ChangeRenamed:
+			fmt.Fprintf(writer, "R  %s -> %s\n", change.Old.Name, change.New.Name)
+		}
+	}
+
+	summary := result.Summary
+	fmt.Fprintf(writer, "%d added, %d removed, %d modified, %d renamed, %d unchanged.\n",
+		summary.Added, summary.Removed, summary.Modified, summary.Renamed, summary.Unchanged)
+}
+
+// isDiffCommand reports whether the command line selects the diff command.
+func isDiffCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == args.DiffCommand
+}
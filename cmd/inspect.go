@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+// inspectOutcome is the machine-readable outcome of the inspect command printed with --output json.
+type inspectOutcome struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+
+	// ErrorCode names the kind of failure, such as "input_unreadable". It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	InputPath string `json:"input_path,omitempty"`
+
+	// Entries lists the entries of the archive in archive order, once the inspection succeeded.
+	Entries []inspectEntry `json:"entries,omitempty"`
+}
+
+// inspectEntry describes an entry of the inspected archive.
+type inspectEntry struct {
+	Path             string    `json:"path"`
+	CompressedSize   int64     `json:"compressed_size"`
+	UncompressedSize int64     `json:"uncompressed_size"`
+	Method           string    `json:"method"`
+	Modified         time.Time `json:"modified"`
+	Mode             string    `json:"mode"`
+	IsDir            bool      `json:"is_dir"`
+	IsSymlink        bool      `json:"is_symlink"`
+	IsMetadata       bool      `json:"is_metadata"`
+
+	// FlattenedName is empty for the entries left out of the output.
+	FlattenedName string `json:"flattened_name,omitempty"`
+
+	// DuplicateGroup is empty for entries that are not deduplicated with another entry.
+	DuplicateGroup string `json:"duplicate_group,omitempty"`
+}
+
+// finish completes the outcome with the exit code of the inspection.
+func (o *inspectOutcome) finish(exitCode int) {
+	o.ExitCode = exitCode
+	o.ErrorCode = errorCode(exitCode)
+	o.Status = statusSuccess
+	if exitCode != exitSuccess {
+		o.Status = statusFailure
+	}
+}
+
+// runInspect lists the entries of the archive given after the inspect command, records them in
+// outcome and returns the exit code. With text output, the entries are printed to output as a table.
+func runInspect(ctx context.Context, arguments []string, output io.Writer, outcome *inspectOutcome) int {
+	inspectOptions, err := args.ParseInspect(arguments)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Arguments", err)
+	}
+
+	outcome.InputPath = inspectOptions.InputZipPath
+
+	entries, err := repackage.Inspect(ctx, inspectOptions.InputZipPath, inspectOptions.RepackageOptions)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Inspect", err)
+	}
+
+	outcome.Entries = make([]inspectEntry, len(entries))
+	for index, entry := range entries {
+		outcome.Entries[index] = inspectEntry{
+			Path:             entry.Path,
+			CompressedSize:   entry.CompressedSize,
+			UncompressedSize: entry.UncompressedSize,
+			Method:           entry.Method,
+			Modified:         entry.Modified,
+			Mode:             entry.Mode.String(),
+			IsDir:            entry.IsDir,
+			IsSymlink:        entry.IsSymlink,
+			IsMetadata:       entry.IsMetadata,
+			FlattenedName:    entry.FlattenedName,
+			DuplicateGroup:   entry.DuplicateGroup,
+		}
+	}
+
+	if inspectOptions.OutputFormat != args.OutputJSON {
+		if err := printEntries(output, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "Output Error: %s\n", err)
+			return exitIO
+		}
+	}
+	return exitSuccess
+}
+
+// printEntries prints the entries as a table with a row per entry. Entries left out of the
+// output show why in the SKIP column instead of a flattened name.
+func printEntries(writer io.Writer, entries []repackage.EntryInfo) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PATH\tSIZE\tCOMPRESSED\tMETHOD\tMODIFIED\tMODE\tSKIP\tNAME\tGROUP")
+
+	for _, entry := range entries {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Path, entry.UncompressedSize, entry.CompressedSize, entry.Method,
+			entry.Modified.Format(time.DateTime), entry.Mode, skipColumn(entry),
+			orDash(entry.FlattenedName), orDash(entry.DuplicateGroup))
+	}
+
+	return table.Flush()
+}
+
+// skipColumn returns why an entry is left out of the output, in the order repackaging checks it.
+func skipColumn(entry repackage.EntryInfo) string {
+	switch {
+	case entry.IsSymlink:
+		return string(repackage.SkipReasonSymlink)
+	case entry.IsDir:
+		return "directory"
+	case entry.IsMetadata:
+		return string(repackage.SkipReasonMetadata)
+	default:
+		return "-"
+	}
+}
+
+// orDash returns the value, or a dash for an empty table cell.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// isInspectCommand reports whether the command line selects the inspect command.
+func isInspectCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == args.InspectCommand
+}
@@ -1,46 +1,326 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/diff"
 	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/sums"
 	"github.com/yash15112001/rezip/internal/validate"
 )
 
+// Exit codes of rezip, so that scripts can branch on the kind of failure.
+const (
+	exitSuccess = 0
+
+	// exitFailure reports an error that no more specific exit code describes.
+	exitFailure = 1
+
+	// exitUsage reports malformed, unknown or inconsistent command-line arguments.
+	exitUsage = 2
+
+	// exitInputUnreadable reports an input archive that is missing or cannot be read.
+	exitInputUnreadable = 3
+
+	// exitConflict reports input entries that cannot be flattened into the same output archive.
+	exitConflict = 4
+
+	// exitIO reports a failure to create or write the output archive.
+	exitIO = 5
+
+	// exitValidationMismatch reports an output archive that does not match the repackaging result.
+	exitValidationMismatch = 6
+
+	// exitValidationError reports a validation that could not be completed.
+	exitValidationError = 7
+
+	// exitBatchFailure reports a batch in which at least one archive failed. The exit code of
+	// each archive is part of the batch summary.
+	exitBatchFailure = 8
+
+	// exitCancelled reports a run interrupted by SIGINT or SIGTERM, following the shell
+	// convention for SIGINT.
+	exitCancelled = 130
+)
+
 func main() {
+	outputFormat := args.RequestedOutputFormat(os.Args[1:])
+
+	exitCode, outcome := run(outputFormat)
+
+	if outputFormat == args.OutputJSON {
+		outcome.finish(exitCode)
+		if err := writeRunResult(os.Stdout, outcome); err != nil {
+			fmt.Fprintf(os.Stderr, "Output Error: %s\n", err)
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+// outcome is the machine-readable outcome of a run, completed with its exit code.
+type outcome interface {
+	finish(exitCode int)
+}
+
+// run repackages and optionally validates the archives given on the command line, and returns
+// the exit code of the program along with its outcome. Status messages are written to the
+// standard error when the standard output is used for the JSON outcome or the validation report.
+func run(outputFormat args.OutputFormat) (int, outcome) {
+	startedAt := time.Now()
+
+	var statusOutput io.Writer = os.Stdout
+	if outputFormat == args.OutputJSON {
+		statusOutput = os.Stderr
+	}
+
+	// Stop between entries on the first interrupt; a second one kills the program as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if isDiffCommand() {
+		outcome := &diffOutcome{}
+		return runDiff(ctx, os.Args[2:], os.Stdout, outcome), outcome
+	}
+	if isInspectCommand() {
+		outcome := &inspectOutcome{}
+		return runInspect(ctx, os.Args[2:], os.Stdout, outcome), outcome
+	}
+	if isVerifySumsCommand() {
+		outcome := &verifySumsOutcome{}
+		return runVerifySums(ctx, os.Args[2:], os.Stdout, outcome), outcome
+	}
+	if isVerifyCommand() {
+		outcome := &verifyOutcome{}
+		return runVerify(ctx, os.Args[2:], os.Stdout, outcome), outcome
+	}
+
 	// Parse and validate command-line arguments.
+	result := &runResult{Phase: phaseArguments}
 	cliOptions, err := args.Parse()
 	if err != nil {
-		exitWithError("Arguments", err)
+		result.fail(phaseArguments, err)
+		return reportError("Arguments", err), result
+	}
+
+	if cliOptions.Quiet {
+		statusOutput = io.Discard
+	}
+
+	logger := newLogger(cliOptions.LogFormat, cliOptions.LogLevel)
+
+	if cliOptions.Batch != nil {
+		summary := &batchSummary{Results: []*runResult{}}
+		return runBatch(ctx, cliOptions, logger, statusOutput, summary), summary
+	}
+
+	result.InputPath = cliOptions.InputZipPath
+	result.MergedPaths = cliOptions.MergeZipPaths
+	result.OutputPath = cliOptions.OutputZipPath
+
+	if !cliOptions.Quiet {
+		progress := newProgressPrinter(logger, cliOptions.LogLevel, os.Stderr)
+		cliOptions.RepackageOptions.Progress = progress.update
+		defer progress.finish()
+	}
+
+	exitCode := processArchive(ctx, cliOptions, logger, statusOutput, result, startedAt)
+	if exitCode != exitSuccess && result.Error != "" {
+		printFailure(os.Stderr, "", cliOptions, result)
+	}
+	return exitCode, result
+}
+
+// processArchive repackages and optionally validates the archive of the options, records its
+// outcome in result and returns the matching exit code. Errors are recorded but not printed.
+func processArchive(ctx context.Context, cliOptions *args.Config, logger *slog.Logger, statusOutput io.Writer,
+	result *runResult, startedAt time.Time,
+) int {
+	cliOptions.RepackageOptions.Logger = logger
+
+	// Share a single hash cache between repackaging and validation, so each entry is read at most once.
+	hashCache := repackage.NewHashCache()
+	cliOptions.RepackageOptions.HashCache = hashCache
+	defer logHashCacheStats(logger, hashCache)
+
+	if cliOptions.Update {
+		cliOptions.RepackageOptions.Previous = previousOutput(cliOptions, logger)
 	}
 
 	// Process the ZIP file (flatten and deduplicate).
-	fileMetadata, err := repackage.Run(cliOptions.InputZipPath, cliOptions.OutputZipPath)
+	result.Phase = phaseRepackaging
+	repackageResult, err := repackage.Run(ctx, cliOptions.InputZipPaths(), cliOptions.OutputZipPath, cliOptions.RepackageOptions)
 	if err != nil {
-		exitWithError("Repackaging", err)
+		result.fail(phaseRepackaging, err)
+		return exitCode(err)
+	}
+	result.Counts = newRunCounts(repackageResult)
+
+	for _, mismatch := range repackageResult.SizeMismatches {
+		logger.Warn("entry declares a wrong size", "path", mismatch.Path,
+			"declared_size", mismatch.DeclaredSize, "actual_size", mismatch.ActualSize)
+	}
+
+	for _, collision := range repackageResult.CaseCollisions {
+		logger.Warn("names only differ by letter case and overwrite each other on case-insensitive file systems",
+			"names", strings.Join(collision, ", "))
+	}
+
+	if cliOptions.SumsPath != "" {
+		err := sums.Write(cliOptions.SumsPath, cliOptions.OutputZipPath, repackageResult,
+			cliOptions.RepackageOptions.HashAlgorithm.OrDefault())
+		if err != nil {
+			result.fail(phaseRepackaging, err)
+			return exitCode(err)
+		}
+		result.SumsPath = cliOptions.SumsPath
 	}
 
 	if !cliOptions.Validate {
-		fmt.Printf("Successfully repackaged %s to %s.\n",
-			cliOptions.InputZipPath, cliOptions.OutputZipPath)
-		return
+		fmt.Fprintf(statusOutput, "Successfully repackaged %s to %s.\n",
+			strings.Join(cliOptions.InputZipPaths(), ", "), cliOptions.OutputZipPath)
+		return exitSuccess
+	}
+
+	validateOptions := cliOptions.ValidateOptions
+	validateOptions.HashCache = hashCache
+	validateOptions.InputZipPath = cliOptions.InputZipPath
+	validateOptions.MergedZipPaths = cliOptions.MergeZipPaths
+	validateOptions.StartedAt = startedAt
+	validateOptions.RepackageOptions = cliOptions.RepackageOptions
+	validateOptions.Logger = logger
+
+	// Keep the standard output free for the report when it is written there.
+	if validateOptions.ReportPath == validate.StdoutReportPath && !cliOptions.Quiet {
+		statusOutput = os.Stderr
+	}
+
+	result.Phase = phaseValidation
+	valid, err := validate.Run(ctx, cliOptions.OutputZipPath, repackageResult, validateOptions)
+	if err != nil {
+		result.fail(phaseValidation, err)
+		if isCancellation(err) {
+			return exitCancelled
+		}
+		return exitValidationError
+	}
+
+	result.Validation = &runValidation{
+		Valid:      valid,
+		ReportPath: validate.ReportPath(cliOptions.OutputZipPath, validateOptions),
+	}
+
+	fmt.Fprintf(statusOutput, "Successfully repackaged %s to %s and performed validation. Validation status: %v\n",
+		strings.Join(cliOptions.InputZipPaths(), ", "), cliOptions.OutputZipPath, valid)
+	if !valid {
+		return exitValidationMismatch
 	}
+	return exitSuccess
+}
 
-	valid, err := validate.Run(cliOptions.OutputZipPath, fileMetadata)
+// previousOutput returns the existing output along with the files listed by its report, so that
+// an update reuses its unchanged entries, or nil to rebuild the output in full when there is no
+// previous output or its report cannot be read.
+func previousOutput(cliOptions *args.Config, logger *slog.Logger) *repackage.PreviousOutput {
+	if _, err := os.Stat(cliOptions.OutputZipPath); err != nil {
+		logger.Info("no previous output to update, building it in full", "path", cliOptions.OutputZipPath)
+		return nil
+	}
+
+	reportPath := validate.ReportPath(cliOptions.OutputZipPath, cliOptions.ValidateOptions)
+	reportedFiles, err := validate.ReadReportFiles(reportPath)
 	if err != nil {
-		fmt.Printf("Successfully repackaged %s to %s, but validation encountered an error: %s\n",
-			cliOptions.InputZipPath, cliOptions.OutputZipPath, err)
-		return
+		logger.Warn("cannot update the previous output, building it in full", "report", reportPath, "error", err)
+		return nil
+	}
+
+	files := make(map[string]repackage.FileInfo, len(reportedFiles))
+	for name, reportedFile := range reportedFiles {
+		files[name] = reportedFile.FileInfo
 	}
+	return &repackage.PreviousOutput{Path: cliOptions.OutputZipPath, Files: files}
+}
 
-	fmt.Printf("Successfully repackaged %s to %s and performed validation. Validation status: %v\n",
-		cliOptions.InputZipPath, cliOptions.OutputZipPath, valid)
+// printFailure prints the error recorded in the result of an archive on a single line
+// starting with prefix.
+func printFailure(writer io.Writer, prefix string, cliOptions *args.Config, result *runResult) {
+	switch result.Phase {
+	case phaseValidation:
+		fmt.Fprintf(writer, "%sRepackaged %s to %s, but validation encountered an error: %s\n",
+			prefix, strings.Join(cliOptions.InputZipPaths(), ", "), cliOptions.OutputZipPath, result.Error)
+	case phaseArguments:
+		fmt.Fprintf(writer, "%sArguments Error: %s\n", prefix, result.Error)
+	default:
+		fmt.Fprintf(writer, "%sRepackaging Error: %s\n", prefix, result.Error)
+	}
 }
 
-// exitWithError prints a formatted error message and exits the program.
-func exitWithError(phase string, err error) {
+// newLogger creates the logger of the run, which writes events of at least the given level to
+// the standard error.
+func newLogger(format args.LogFormat, level slog.Level) *slog.Logger {
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	if format == args.LogJSON {
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOptions))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, handlerOptions))
+}
+
+// logHashCacheStats logs how often entry checksums were served from the hash cache.
+func logHashCacheStats(logger *slog.Logger, hashCache *repackage.HashCache) {
+	stats := hashCache.Stats()
+	logger.Info("hash cache", "hits", stats.Hits, "misses", stats.Misses)
+}
+
+// reportError prints a formatted error message and returns the exit code matching the error.
+func reportError(phase string, err error) int {
 	fmt.Fprintf(os.Stderr, "%s Error: %s\n", phase, err)
-	os.Exit(1)
+	return exitCode(err)
+}
+
+// exitCode maps the typed errors of the argument parsing, repackaging, comparison and checksum
+// verification phases to exit codes.
+func exitCode(err error) int {
+	var (
+		usageErr           *args.UsageError
+		argsInputErr       *args.InputError
+		argsOutputErr      *args.OutputError
+		repackageInputErr  *repackage.InputError
+		conflictErr        *repackage.ConflictError
+		repackageOutputErr *repackage.OutputError
+		diffInputErr       *diff.InputError
+		sumsInputErr       *sums.InputError
+		sumsOutputErr      *sums.OutputError
+	)
+
+	switch {
+	case isCancellation(err):
+		return exitCancelled
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &argsInputErr), errors.As(err, &repackageInputErr), errors.As(err, &diffInputErr),
+		errors.As(err, &sumsInputErr):
+		return exitInputUnreadable
+	case errors.As(err, &conflictErr):
+		return exitConflict
+	case errors.As(err, &argsOutputErr), errors.As(err, &repackageOutputErr), errors.As(err, &sumsOutputErr):
+		return exitIO
+	default:
+		return exitFailure
+	}
+}
+
+// isCancellation reports whether an error stems from the run being interrupted.
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/diff"
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/sums"
+)
+
+func TestExitCode(t *testing.T) {
+	cause := errors.New("cause")
+
+	t.Run("Successfully maps typed errors to exit codes and error names", func(t *testing.T) {
+		for _, testCase := range []struct {
+			err       error
+			exitCode  int
+			errorCode string
+		}{
+			{err: &args.UsageError{Err: cause}, exitCode: exitUsage, errorCode: "usage"},
+			{err: &args.InputError{Err: cause}, exitCode: exitInputUnreadable, errorCode: "input_unreadable"},
+			{err: &args.OutputError{Err: cause}, exitCode: exitIO, errorCode: "io"},
+			{err: &repackage.InputError{Err: cause}, exitCode: exitInputUnreadable, errorCode: "input_unreadable"},
+			{err: &repackage.ConflictError{Err: cause}, exitCode: exitConflict, errorCode: "conflict"},
+			{err: &repackage.OutputError{Err: cause}, exitCode: exitIO, errorCode: "io"},
+			{err: &diff.InputError{Err: cause}, exitCode: exitInputUnreadable, errorCode: "input_unreadable"},
+			{err: &sums.InputError{Err: cause}, exitCode: exitInputUnreadable, errorCode: "input_unreadable"},
+			{err: &sums.OutputError{Err: cause}, exitCode: exitIO, errorCode: "io"},
+			{err: context.Canceled, exitCode: exitCancelled, errorCode: "cancelled"},
+			{err: &repackage.InputError{Err: context.Canceled}, exitCode: exitCancelled, errorCode: "cancelled"},
+			{err: fmt.Errorf("wrapped: %w", &repackage.ConflictError{Err: cause}), exitCode: exitConflict, errorCode: "conflict"},
+			{err: cause, exitCode: exitFailure, errorCode: "failure"},
+		} {
+			code := exitCode(testCase.err)
+
+			assert.Equal(t, testCase.exitCode, code, "Exit code of %T", testCase.err)
+			assert.Equal(t, testCase.errorCode, errorCode(code), "Error name of %T", testCase.err)
+		}
+	})
+
+	t.Run("Successfully names the exit codes without a typed error", func(t *testing.T) {
+		assert.Equal(t, "", errorCode(exitSuccess))
+		assert.Equal(t, "validation_mismatch", errorCode(exitValidationMismatch))
+		assert.Equal(t, "validation_error", errorCode(exitValidationError))
+		assert.Equal(t, "batch_failure", errorCode(exitBatchFailure))
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+// Phases of a run, reported as the phase in which the run ended.
+const (
+	phaseArguments   = "arguments"
+	phaseRepackaging = "repackaging"
+	phaseValidation  = "validation"
+)
+
+// Statuses of a run.
+const (
+	statusSuccess = "success"
+	statusFailure = "failure"
+)
+
+// runResult is the machine-readable outcome of a run printed with --output json.
+type runResult struct {
+	Status string `json:"status"`
+
+	// Phase is the phase in which the run ended, either because it failed or because it was the
+	// last one requested.
+	Phase string `json:"phase"`
+
+	ExitCode int `json:"exit_code"`
+
+	// ErrorCode names the kind of failure, such as "conflict". It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	InputPath string `json:"input_path,omitempty"`
+
+	// MergedPaths are the archives merged with the input archive, if any.
+	MergedPaths []string `json:"merged_paths,omitempty"`
+
+	OutputPath string `json:"output_path,omitempty"`
+
+	// SumsPath is the checksum file listing the output files, only present once it was written.
+	SumsPath string `json:"sums_path,omitempty"`
+
+	// Counts is only present once repackaging succeeded.
+	Counts *runCounts `json:"counts,omitempty"`
+
+	// Validation is only present when validation was requested and completed.
+	Validation *runValidation `json:"validation,omitempty"`
+}
+
+// runCounts counts the files of the output archive and the input entries left out of it.
+type runCounts struct {
+	Files          int `json:"files"`
+	Skipped        int `json:"skipped"`
+	Duplicates     int `json:"duplicates"`
+	Aliases        int `json:"aliases"`
+	SizeMismatches int `json:"size_mismatches"`
+	CaseCollisions int `json:"case_collisions"`
+}
+
+// runValidation is the outcome of the validation of the output archive.
+type runValidation struct {
+	Valid      bool   `json:"valid"`
+	ReportPath string `json:"report_path"`
+}
+
+// newRunCounts counts the files and skipped entries of a repackaging result.
+func newRunCounts(result *repackage.Result) *runCounts {
+	counts := &runCounts{
+		Files:          len(result.Files),
+		Skipped:        len(result.Skipped),
+		SizeMismatches: len(result.SizeMismatches),
+		CaseCollisions: len(result.CaseCollisions),
+	}
+
+	for _, fileInfo := range result.Files {
+		counts.Duplicates += len(fileInfo.Duplicates)
+		counts.Aliases += len(fileInfo.Aliases)
+	}
+
+	return counts
+}
+
+// fail records the error that ended the run in the given phase.
+func (r *runResult) fail(phase string, err error) {
+	r.Phase = phase
+	r.Error = err.Error()
+}
+
+// finish completes the result with the exit code of the run.
+func (r *runResult) finish(exitCode int) {
+	r.ExitCode = exitCode
+	r.ErrorCode = errorCode(exitCode)
+	r.Status = statusSuccess
+	if exitCode != exitSuccess {
+		r.Status = statusFailure
+	}
+}
+
+// errorCode returns the name of the kind of failure reported by an exit code.
+func errorCode(exitCode int) string {
+	switch exitCode {
+	case exitSuccess:
+		return ""
+	case exitUsage:
+		return "usage"
+	case exitInputUnreadable:
+		return "input_unreadable"
+	case exitConflict:
+		return "conflict"
+	case exitIO:
+		return "io"
+	case exitValidationMismatch:
+		return "validation_mismatch"
+	case exitValidationError:
+		return "validation_error"
+	case exitBatchFailure:
+		return "batch_failure"
+	case exitCancelled:
+		return "cancelled"
+	default:
+		return "failure"
+	}
+}
+
+// writeRunResult writes the result as a single line of JSON.
+func writeRunResult(writer io.Writer, result outcome) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(result)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/ziptest"
+)
+
+func TestWriteRunResult(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// runJSON runs rezip with the arguments and --output json, and decodes the printed outcome.
+	runJSON := func(t *testing.T, arguments ...string) map[string]any {
+		originalArgs := os.Args
+		defer func() { os.Args = originalArgs }()
+		os.Args = append([]string{"rezip", "--output", "json", "--quiet"}, arguments...)
+
+		exitCode, outcome := run(args.OutputJSON)
+		outcome.finish(exitCode)
+
+		var output bytes.Buffer
+		require.NoError(t, writeRunResult(&output, outcome))
+		assert.Equal(t, 1, bytes.Count(output.Bytes(), []byte("\n")), "Outcome should be a single line")
+
+		var document map[string]any
+		require.NoError(t, json.Unmarshal(output.Bytes(), &document))
+		return document
+	}
+
+	t.Run("Returns error outcome for invalid arguments", func(t *testing.T) {
+		document := runJSON(t, "--unknown")
+
+		assert.Equal(t, "failure", document["status"])
+		assert.Equal(t, "arguments", document["phase"])
+		assert.Equal(t, float64(exitUsage), document["exit_code"])
+		assert.Equal(t, "usage", document["error_code"])
+		assert.Contains(t, document["error"], "unknown option")
+		assert.NotContains(t, document, "counts")
+	})
+
+	t.Run("Returns error outcome for conflicting entries", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "conflict.zip")
+		require.NoError(t, ziptest.Write(inputPath, [][2]string{
+			{"a/data.txt", "first"},
+			{"b/data.txt", "other"},
+		}))
+		outputPath := filepath.Join(tempDir, "conflict_output.zip")
+
+		document := runJSON(t, inputPath, outputPath)
+
+		assert.Equal(t, "failure", document["status"])
+		assert.Equal(t, "repackaging", document["phase"])
+		assert.Equal(t, float64(exitConflict), document["exit_code"])
+		assert.Equal(t, "conflict", document["error_code"])
+		assert.Contains(t, document["error"], `files with name "data.txt" have identical sizes but differing content`)
+		assert.Equal(t, inputPath, document["input_path"])
+		assert.Equal(t, outputPath, document["output_path"])
+		assert.NotContains(t, document, "counts")
+	})
+
+	t.Run("Successfully writes the outcome of a run", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "input.zip")
+		require.NoError(t, ziptest.Write(inputPath, [][2]string{
+			{"a/logo.png", "png bytes"},
+			{"b/logo.png", "png bytes"},
+			{"docs/readme.txt", "readme"},
+			{".DS_Store", "metadata"},
+		}))
+		outputPath := filepath.Join(tempDir, "output.zip")
+
+		document := runJSON(t, inputPath, outputPath)
+
+		assert.Equal(t, "success", document["status"])
+		assert.Equal(t, "repackaging", document["phase"])
+		assert.Equal(t, float64(exitSuccess), document["exit_code"])
+		assert.NotContains(t, document, "error_code")
+		assert.NotContains(t, document, "error")
+		assert.Equal(t, map[string]any{
+			"files":           float64(2),
+			"skipped":         float64(2),
+			"duplicates":      float64(1),
+			"aliases":         float64(0),
+			"size_mismatches": float64(0),
+			"case_collisions": float64(0),
+		}, document["counts"])
+		assert.NotContains(t, document, "validation")
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+const (
+	// progressBarInterval is the minimum time between two redraws of the progress bar.
+	progressBarInterval = 100 * time.Millisecond
+
+	// progressLogInterval is the time between two progress log events.
+	progressLogInterval = 5 * time.Second
+
+	// progressBarWidth is the number of characters of the progress bar itself.
+	progressBarWidth = 30
+)
+
+// progressPrinter shows the progress of repackaging, either as a progress bar redrawn on an
+// interactive terminal, or as periodic log events.
+type progressPrinter struct {
+	// terminal is the terminal the progress bar is drawn on, or nil to log progress events.
+	terminal io.Writer
+	logger   *slog.Logger
+
+	lastPrinted time.Time
+	lastPhase   repackage.ProgressPhase
+}
+
+// newProgressPrinter draws a progress bar when stderr, the standard error, is a terminal and
+// log events of the run are not verbose, as they would be interleaved with the bar. Otherwise
+// progress is logged every progressLogInterval as a warning, so that long runs in CI or piped
+// to a file show progress at the default verbosity while short runs log none.
+func newProgressPrinter(logger *slog.Logger, logLevel slog.Level, stderr *os.File) *progressPrinter {
+	printer := &progressPrinter{logger: logger}
+	if logLevel > slog.LevelInfo && isTerminal(stderr) {
+		printer.terminal = stderr
+	} else {
+		// The first event is only logged once the run has lasted an interval.
+		printer.lastPrinted = time.Now()
+	}
+	return printer
+}
+
+// update redraws the progress bar if the phase changed, the phase is complete, or enough time
+// passed since the last redraw. Without a terminal, progress is only logged once enough time
+// passed since the last event.
+func (p *progressPrinter) update(progress repackage.Progress) {
+	now := time.Now()
+
+	if p.terminal == nil {
+		if now.Sub(p.lastPrinted) < progressLogInterval {
+			return
+		}
+		p.lastPrinted = now
+		p.logger.Warn("progress", "phase", progress.Phase, "entries", progress.Entries,
+			"total_entries", progress.TotalEntries, "bytes_read", progress.BytesRead, "bytes_written", progress.BytesWritten)
+		return
+	}
+
+	isPhaseComplete := progress.Entries == progress.TotalEntries
+	if progress.Phase == p.lastPhase && !isPhaseComplete && now.Sub(p.lastPrinted) < progressBarInterval {
+		return
+	}
+	p.lastPhase = progress.Phase
+	p.lastPrinted = now
+
+	fmt.Fprintf(p.terminal, "\r\033[K%s", progressBar(progress))
+}
+
+// finish erases the progress bar, if one was drawn.
+func (p *progressPrinter) finish() {
+	if p.terminal != nil && !p.lastPrinted.IsZero() {
+		fmt.Fprint(p.terminal, "\r\033[K")
+	}
+}
+
+// progressBar renders a progress report on a single line, such as
+// "writing  [#######-------]  50% 10/20 entries, 1.5 MiB read, 1.0 MiB written".
+func progressBar(progress repackage.Progress) string {
+	ratio := 1.0
+	if progress.TotalEntries > 0 {
+		ratio = float64(progress.Entries) / float64(progress.TotalEntries)
+	}
+	filled := int(ratio * progressBarWidth)
+
+	return fmt.Sprintf("%-8s [%s%s] %3d%% %d/%d entries, %s read, %s written",
+		progress.Phase, strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		int(ratio*100), progress.Entries, progress.TotalEntries,
+		formatBytes(progress.BytesRead), formatBytes(progress.BytesWritten))
+}
+
+// formatBytes formats a number of bytes with a binary unit, such as "1.5 GiB".
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	divisor, exponent := int64(unit), 0
+	for quotient := bytes / unit; quotient >= unit && exponent < 5; quotient /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(divisor), "KMGTPE"[exponent])
+}
+
+// isTerminal reports whether a file is an interactive terminal.
+func isTerminal(file *os.File) bool {
+	fileInfo, err := file.Stat()
+	return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestProgressPrinter(t *testing.T) {
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	require.NoError(t, err)
+	defer stderr.Close()
+
+	t.Run("Successfully logs periodic progress without a terminal at the default level", func(t *testing.T) {
+		var output bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		printer := newProgressPrinter(logger, slog.LevelWarn, stderr)
+
+		printer.update(repackage.Progress{Phase: repackage.ProgressScanning, Entries: 0, TotalEntries: 4})
+		printer.update(repackage.Progress{Phase: repackage.ProgressScanning, Entries: 4, TotalEntries: 4})
+		assert.Empty(t, output.String(), "Phase start and end should not be logged before an interval passed")
+
+		printer.lastPrinted = time.Now().Add(-progressLogInterval)
+		printer.update(repackage.Progress{Phase: repackage.ProgressWriting, Entries: 3, TotalEntries: 4, BytesWritten: 10})
+		printer.update(repackage.Progress{Phase: repackage.ProgressWriting, Entries: 4, TotalEntries: 4, BytesWritten: 12})
+		printer.finish()
+
+		assert.Nil(t, printer.terminal)
+		lines := bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n"))
+		require.Len(t, lines, 1, "Reports within the interval should be skipped")
+		assert.Contains(t, string(lines[0]), "level=WARN msg=progress phase=writing entries=3 total_entries=4 bytes_read=0 bytes_written=10")
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/validate"
+)
+
+// verifyOutcome is the machine-readable outcome of the verify command printed with --output json.
+type verifyOutcome struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+
+	// ErrorCode names the kind of failure, such as "validation_mismatch". It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	OutputPath string `json:"output_path,omitempty"`
+	ReportPath string `json:"report_path,omitempty"`
+
+	// Verification holds the checks of the signature and entries, which are only present once
+	// the verification completed.
+	*validate.Verification
+}
+
+// finish completes the outcome with the exit code of the verification.
+func (o *verifyOutcome) finish(exitCode int) {
+	o.ExitCode = exitCode
+	o.ErrorCode = errorCode(exitCode)
+	o.Status = statusSuccess
+	if exitCode != exitSuccess {
+		o.Status = statusFailure
+	}
+}
+
+// runVerify checks the archive given after the verify command against its signed validation
+// report, records the checks in outcome and returns the exit code. With text output, the checks
+// are printed to output.
+func runVerify(ctx context.Context, arguments []string, output io.Writer, outcome *verifyOutcome) int {
+	verifyOptions, err := args.ParseVerify(arguments)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Arguments", err)
+	}
+
+	outcome.OutputPath = verifyOptions.OutputZipPath
+	outcome.ReportPath = verifyOptions.ReportPath
+
+	verification, err := validate.Verify(ctx, verifyOptions.OutputZipPath, verifyOptions.ReportPath, verifyOptions.PublicKey)
+	if err != nil {
+		outcome.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "Verification Error: %s\n", err)
+		if isCancellation(err) {
+			return exitCancelled
+		}
+		return exitValidationError
+	}
+
+	outcome.Verification = verification
+
+	if verifyOptions.OutputFormat != args.OutputJSON {
+		printVerification(output, verification)
+	}
+	if !verification.Valid {
+		return exitValidationMismatch
+	}
+	return exitSuccess
+}
+
+// printVerification prints the outcome of every file and problem, followed by the validity of
+// the signature and of the report.
+func printVerification(writer io.Writer, verification *validate.Verification) {
+	for _, file := range verification.Files {
+		status := "OK"
+		if !file.Match {
+			status = "FAILED"
+		}
+		fmt.Fprintf(writer, "%s: %s\n", file.Name, status)
+	}
+	for _, problem := range verification.Problems {
+		fmt.Fprintf(writer, "%s: %s (%s)\n", problem.Name, strings.ToUpper(problem.Kind), problem.Problem)
+	}
+
+	fmt.Fprintf(writer, "Signature %s, report %s, %d files checked, %d problems.\n",
+		validity(verification.SignatureValid), validity(verification.ReportValid),
+		len(verification.Files), len(verification.Problems))
+}
+
+// validity describes a boolean validity in words.
+func validity(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "invalid"
+}
+
+// isVerifyCommand reports whether the command line selects the verify command.
+func isVerifyCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == args.VerifyCommand
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yash15112001/rezip/internal/args"
+	"github.com/yash15112001/rezip/internal/sums"
+)
+
+// verifySumsOutcome is the machine-readable outcome of the verify-sums command printed with
+// --output json.
+type verifySumsOutcome struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+
+	// ErrorCode names the kind of failure, such as "validation_mismatch". It is empty on success.
+	ErrorCode string `json:"error_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	ZipPath  string `json:"zip_path,omitempty"`
+	SumsPath string `json:"sums_path,omitempty"`
+
+	// Result holds the summary and checks, which are only present once the verification completed.
+	*sums.Result
+}
+
+// finish completes the outcome with the exit code of the verification.
+func (o *verifySumsOutcome) finish(exitCode int) {
+	o.ExitCode = exitCode
+	o.ErrorCode = errorCode(exitCode)
+	o.Status = statusSuccess
+	if exitCode != exitSuccess {
+		o.Status = statusFailure
+	}
+}
+
+// runVerifySums checks the archive given after the verify-sums command against the checksum
+// file, records the checks in outcome and returns the exit code. With text output, the checks
+// are printed to output.
+func runVerifySums(ctx context.Context, arguments []string, output io.Writer, outcome *verifySumsOutcome) int {
+	verifyOptions, err := args.ParseVerifySums(arguments)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Arguments", err)
+	}
+
+	outcome.ZipPath = verifyOptions.ZipPath
+	outcome.SumsPath = verifyOptions.SumsPath
+
+	result, err := sums.Verify(ctx, verifyOptions.ZipPath, verifyOptions.SumsPath, verifyOptions.HashAlgorithm)
+	if err != nil {
+		outcome.Error = err.Error()
+		return reportError("Verification", err)
+	}
+
+	outcome.Result = result
+
+	if verifyOptions.OutputFormat != args.OutputJSON {
+		printChecks(output, result)
+	}
+	if !result.Valid {
+		return exitValidationMismatch
+	}
+	return exitSuccess
+}
+
+// printChecks prints the outcome of every listed file as sha256sum -c does, followed by the
+// number of files of each status.
+func printChecks(writer io.Writer, result *sums.Result) {
+	for _, check := range result.Checks {
+		fmt.Fprintf(writer, "%s: %s\n", check.Name, strings.ToUpper(string(check.Status)))
+	}
+
+	summary := result.Summary
+	fmt.Fprintf(writer, "%d ok, %d failed, %d missing, %d unlisted.\n",
+		summary.OK, summary.Failed, summary.Missing, summary.Unlisted)
+}
+
+// isVerifySumsCommand reports whether the command line selects the verify-sums command.
+func isVerifySumsCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == args.VerifySumsCommand
+}
@@ -3,14 +3,147 @@ package args
 import (
 	"archive/zip"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/yash15112001/rezip/internal/batch"
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/validate"
 )
 
 const (
 	// validateFlag is the flag such that, if provided, the resulting zip will be validated after repackaging.
 	validateFlag = "--validate"
 
+	// verifySizesFlag is the flag such that, if provided, actual decompressed sizes are measured
+	// instead of trusting the sizes declared in the input zip headers.
+	verifySizesFlag = "--verify-sizes"
+
+	// caseInsensitiveFlag is the flag such that, if provided, names only differing by letter case are deduplicated.
+	caseInsensitiveFlag = "--case-insensitive"
+
+	// warnCaseCollisionsFlag is the flag such that, if provided, names only differing by letter case are reported.
+	warnCaseCollisionsFlag = "--warn-case-collisions"
+
+	// verboseFlag and verboseShortFlag are the flags such that, if provided, every skipped and dropped entry
+	// and statistics about the run are logged.
+	verboseFlag      = "--verbose"
+	verboseShortFlag = "-v"
+
+	// debugShortFlag is the flag such that, if provided, every entry read and written is logged as well.
+	debugShortFlag = "-vv"
+
+	// quietFlag is the flag such that, if provided, only errors are printed.
+	quietFlag = "--quiet"
+
+	// logFormatOption selects the format of log events (text or json).
+	logFormatOption = "--log-format"
+
+	// dedupeContentFlag is the flag such that, if provided, files with identical content are only kept once.
+	dedupeContentFlag = "--dedupe-content"
+
+	// canonicalOption selects which file is kept by content deduplication (first, shortest or lexical).
+	canonicalOption = "--canonical"
+
+	// namesOption selects the policy used to sanitize flattened names (posix, windows or portable).
+	namesOption = "--names"
+
+	// normalizeOption selects the Unicode normalization form applied to flattened names (none, nfc or nfd).
+	normalizeOption = "--normalize"
+
+	// legacyEncodingOption selects how names stored without the ZIP UTF-8 flag are decoded
+	// (none, cp437 or shift-jis).
+	legacyEncodingOption = "--legacy-encoding"
+
+	// keepDepthOption sets the number of trailing path components kept in flattened names.
+	keepDepthOption = "--keep-depth"
+
+	// stripPrefixOption sets a leading directory removed from entry paths before flattening.
+	stripPrefixOption = "--strip-prefix"
+
+	// renameOption sets a template computing flattened names, such as "{parent}_{base}".
+	renameOption = "--rename"
+
+	// rewriteOption adds a "PATTERN=>REPLACEMENT" rule rewriting entry paths. It may be repeated.
+	rewriteOption = "--rewrite"
+
+	// hashOption selects the checksum algorithm of the run (sha256, sha512, blake2b or crc32).
+	hashOption = "--hash"
+
+	// sumsOption sets the path of a checksum file in the format of sha256sum listing the output
+	// files and the output archive.
+	sumsOption = "--sums"
+
+	// embedManifestFlag is the flag such that, if provided, a manifest is embedded in the output zip.
+	embedManifestFlag = "--embed-manifest"
+
+	// manifestFormatOption selects the format of the embedded manifest (json or csv).
+	manifestFormatOption = "--manifest-format"
+
+	// validateInputFlag is the flag such that, if provided, the output zip is validated against the
+	// input zip, which is read again. It implies validation.
+	validateInputFlag = "--validate-input"
+
+	// reportOption sets the path of the validation report, or "-" for the standard output. It implies validation.
+	reportOption = "--report"
+
+	// reportFormatOption selects the format of the validation report (json, ndjson, csv, junit or markdown).
+	// It implies validation.
+	reportFormatOption = "--report-format"
+
+	// signKeyOption sets the PEM file of the ed25519 private key signing the JSON validation report.
+	// It implies validation.
+	signKeyOption = "--sign-key"
+
+	// outputOption selects how the outcome of the run is printed (text or json).
+	outputOption = "--output"
+
+	// updateFlag is the flag such that, if provided, the entries of the existing output whose source
+	// entries are unchanged are copied instead of being processed again. It implies validation, so
+	// that the next update can read the report.
+	updateFlag = "--update"
+
+	// mergeOption adds an archive whose entries are merged with the input archive. It may be repeated.
+	mergeOption = "--merge"
+
+	// batchFlag is the flag such that, if provided, every positional argument is an archive, a
+	// directory of archives or a glob pattern, and all the archives are repackaged.
+	batchFlag = "--batch"
+
+	// outputDirOption sets the directory the archives of a batch are written to under their own name.
+	outputDirOption = "--output-dir"
+
+	// outputTemplateOption sets a template computing the output path of each archive of a batch,
+	// such as "{dir}/{name}_flat.zip".
+	outputTemplateOption = "--output-template"
+
+	// jobsOption sets the maximum number of archives of a batch processed at the same time.
+	jobsOption = "--jobs"
+
+	// summaryOption sets the path of the JSON summary of a batch.
+	summaryOption = "--summary"
+
+	// usage describes the command-line syntax of rezip.
+	usage = "rezip <input.zip> <output.zip> [options]\n   or: rezip " + batchFlag + " <input.zip|dir|pattern>... (" +
+		outputDirOption + " dir | " + outputTemplateOption + " template) [" + jobsOption + " N] [" + summaryOption + " path] [options]\n" +
+		"   or: " + diffUsage + "\n" +
+		"   or: " + inspectUsage + "\n" +
+		"   or: " + verifySumsUsage + "\n" +
+		"   or: " + verifyUsage + "\n" +
+		"options: [" + mergeOption + " path]... [" + updateFlag + "] [" + validateFlag + "] [" + validateInputFlag + "] [" + reportOption + " path|-] [" +
+		reportFormatOption + " json|ndjson|csv|junit|markdown] [" + signKeyOption + " key.pem] [" + outputOption + " text|json] [" + verboseShortFlag + "|" + verboseFlag + "|" + debugShortFlag + "|" + quietFlag + "] [" +
+		logFormatOption + " text|json] [" +
+		verifySizesFlag + "] [" + hashOption + " sha256|sha512|blake2b|crc32] [" + sumsOption + " path] [" +
+		namesOption + " posix|windows|portable] [" + caseInsensitiveFlag + " | " + warnCaseCollisionsFlag + "] [" +
+		normalizeOption + " none|nfc|nfd] [" + legacyEncodingOption + " none|cp437|shift-jis] [" +
+		dedupeContentFlag + " [" + canonicalOption + " first|shortest|lexical]] [" +
+		keepDepthOption + " N] [" + stripPrefixOption + " path] [" + renameOption + " template] [" +
+		rewriteOption + " pattern=>replacement]... [" + embedManifestFlag + " [" + manifestFormatOption + " json|csv]]"
+
 	// readPermissionBit is the bit representing read permission for the file owner (0400 in octal).
 	readPermissionBit = 1 << 8
 
@@ -18,48 +151,508 @@ const (
 	writePermissionBit = 1 << 7
 )
 
+// OutputFormat selects how the outcome of a run is printed.
+type OutputFormat string
+
+const (
+	// OutputText prints human-readable status messages.
+	OutputText OutputFormat = "text"
+
+	// OutputJSON prints a single JSON result object to the standard output and status messages
+	// to the standard error.
+	OutputJSON OutputFormat = "json"
+)
+
+// ParseOutputFormat converts a command-line value into an OutputFormat.
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch format := OutputFormat(value); format {
+	case OutputText, OutputJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q: expected %s or %s", value, OutputText, OutputJSON)
+	}
+}
+
+// RequestedOutputFormat returns the output format selected by the command-line arguments, or
+// OutputText when none is valid. Unlike Parse it never fails, so that argument errors can be
+// printed in the requested format.
+func RequestedOutputFormat(arguments []string) OutputFormat {
+	requested := OutputText
+	for index, argument := range arguments {
+		value, hasValue := strings.CutPrefix(argument, outputOption+"=")
+		if argument == outputOption && index+1 < len(arguments) {
+			value, hasValue = arguments[index+1], true
+		}
+		if format, err := ParseOutputFormat(value); hasValue && err == nil {
+			requested = format
+		}
+	}
+	return requested
+}
+
+// LogFormat selects the format of log events.
+type LogFormat string
+
+const (
+	// LogText writes log events as key=value pairs.
+	LogText LogFormat = "text"
+
+	// LogJSON writes log events as JSON objects, one per line.
+	LogJSON LogFormat = "json"
+)
+
+// ParseLogFormat converts a command-line value into a LogFormat.
+func ParseLogFormat(value string) (LogFormat, error) {
+	switch format := LogFormat(value); format {
+	case LogText, LogJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q: expected %s or %s", value, LogText, LogJSON)
+	}
+}
+
 // Config holds the parsed command-line arguments for rezip such as input, output zip path and validate flag.
 type Config struct {
 	InputZipPath  string
 	OutputZipPath string
 	Validate      bool
+	Verbose       bool
+
+	// MergeZipPaths are archives whose entries are merged with those of the input archive, in order.
+	MergeZipPaths []string
+
+	// Update reuses the unchanged entries of the existing output, listed by its validation report.
+	Update bool
+
+	// OutputFormat selects how the outcome of the run is printed. Defaults to OutputText.
+	OutputFormat OutputFormat
+
+	// SigningKeyPath is the PEM file of the private key signing the validation report, loaded into
+	// ValidateOptions.SigningKey by Parse, or empty to not sign the report.
+	SigningKeyPath string
+
+	// SumsPath is the path of the checksum file listing the output files and the output archive,
+	// or empty to not write one.
+	SumsPath string
+
+	// LogLevel is the minimum level of the logged events: warnings by default, informational
+	// events with -v, debug events with -vv and only errors with --quiet.
+	LogLevel slog.Level
+
+	// Quiet omits status messages, so that only errors are printed.
+	Quiet bool
+
+	// LogFormat selects the format of log events. Defaults to LogText.
+	LogFormat LogFormat
+
+	// Batch holds the inputs and outputs of batch mode, or is nil when a single archive is repackaged.
+	Batch *BatchConfig
+
+	// RepackageOptions holds the options that control flattening and deduplication.
+	RepackageOptions repackage.Options
+
+	// ValidateOptions holds the options that control where and how the validation report is written.
+	ValidateOptions validate.Options
 }
 
-// Parse validates command line arguments and returns a Config.
+// BatchConfig holds the command-line arguments specific to batch mode.
+type BatchConfig struct {
+	// Inputs are archives, directories of archives or glob patterns, as given on the command line.
+	Inputs []string
+
+	// OutputDirectory is the directory archives are written to under their own name. Exactly one
+	// of OutputDirectory and OutputTemplate is set.
+	OutputDirectory string
+
+	// OutputTemplate computes the output path of each archive from its {name} and {dir}.
+	OutputTemplate string
+
+	// Jobs is the maximum number of archives processed at the same time. Defaults to the number of CPUs.
+	Jobs int
+
+	// SummaryPath is the path of the JSON summary of the batch, or empty to not write one.
+	SummaryPath string
+}
+
+// UsageError reports command-line arguments that are malformed, unknown or inconsistent.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// InputError reports an input zip file or key file that is missing or cannot be read.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// OutputError reports an output directory that is missing or cannot be written to.
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string { return e.Err.Error() }
+func (e *OutputError) Unwrap() error { return e.Err }
+
+// Parse validates command line arguments and returns a Config. Errors are a *UsageError,
+// an *InputError or an *OutputError depending on the argument at fault. In batch mode the
+// archives are not checked, as they are only known once the inputs are expanded.
 func Parse() (*Config, error) {
-	arguments := os.Args
-	if len(arguments) < 3 || len(arguments) > 4 {
-		return nil, fmt.Errorf("invalid number of arguments. Usage: rezip <input.zip> <output.zip> [%s]", validateFlag)
+	cliOptions, err := parseArguments(os.Args[1:])
+	if err != nil {
+		return nil, &UsageError{Err: err}
 	}
 
-	cliOptions := &Config{
-		InputZipPath:  arguments[1],
-		OutputZipPath: arguments[2],
+	if cliOptions.SigningKeyPath != "" {
+		signingKey, err := validate.ReadSigningKey(cliOptions.SigningKeyPath)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		cliOptions.ValidateOptions.SigningKey = signingKey
 	}
 
-	if len(arguments) == 4 {
-		if arguments[3] != validateFlag {
-			return nil, fmt.Errorf("unknown option [%q]: only [%s] is supported as an optional argument",
-				arguments[3], validateFlag)
-		}
-		cliOptions.Validate = true
+	if cliOptions.Batch != nil {
+		return cliOptions, nil
 	}
 
-	if err := validateInputFile(cliOptions.InputZipPath); err != nil {
+	if err := ValidatePaths(cliOptions.InputZipPath, cliOptions.OutputZipPath); err != nil {
 		return nil, err
 	}
 
-	if err := validateOutputDirectory(cliOptions.OutputZipPath); err != nil {
-		return nil, err
+	for _, mergePath := range cliOptions.MergeZipPaths {
+		if err := ValidatePaths(mergePath, cliOptions.OutputZipPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateDistinctInputs(append([]string{cliOptions.InputZipPath}, cliOptions.MergeZipPaths...)); err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	return cliOptions, nil
+}
+
+// InputZipPaths returns the input archive followed by the archives merged with it.
+func (c *Config) InputZipPaths() []string {
+	return append([]string{c.InputZipPath}, c.MergeZipPaths...)
+}
+
+// ValidatePaths checks that the input zip file can be read, that the output can be written, and
+// that they are distinct files. Errors are a *UsageError, an *InputError or an *OutputError.
+func ValidatePaths(inputPath, outputPath string) error {
+	if err := validateInputFile(inputPath); err != nil {
+		return &InputError{Err: err}
+	}
+
+	if err := validateOutputDirectory(outputPath); err != nil {
+		return &OutputError{Err: err}
+	}
+
+	if err := validateDistinctPaths(inputPath, outputPath); err != nil {
+		return &UsageError{Err: err}
 	}
 
-	if err := validateDistinctPaths(cliOptions.InputZipPath, cliOptions.OutputZipPath); err != nil {
+	return nil
+}
+
+// parseArguments parses the options and positional arguments of the command line, without
+// checking the files they refer to.
+func parseArguments(arguments []string) (*Config, error) {
+	cliOptions := &Config{LogLevel: slog.LevelWarn}
+	batchOptions := &BatchConfig{Jobs: runtime.NumCPU()}
+	var isBatch bool
+
+	positionalArguments, err := scanArguments(arguments, takesValue, usage, func(option, value string) error {
+		switch option {
+		case validateFlag:
+			cliOptions.Validate = true
+		case validateInputFlag:
+			cliOptions.Validate = true
+			cliOptions.ValidateOptions.VerifyInput = true
+		case reportOption:
+			cliOptions.Validate = true
+			cliOptions.ValidateOptions.ReportPath = value
+		case signKeyOption:
+			cliOptions.Validate = true
+			cliOptions.SigningKeyPath = value
+		case reportFormatOption:
+			reportFormat, err := validate.ParseReportFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.Validate = true
+			cliOptions.ValidateOptions.ReportFormat = reportFormat
+		case outputOption:
+			outputFormat, err := ParseOutputFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.OutputFormat = outputFormat
+		case updateFlag:
+			cliOptions.Update = true
+			cliOptions.Validate = true
+		case mergeOption:
+			cliOptions.MergeZipPaths = append(cliOptions.MergeZipPaths, value)
+		case batchFlag:
+			isBatch = true
+		case outputDirOption:
+			batchOptions.OutputDirectory = value
+		case outputTemplateOption:
+			if err := batch.ValidateOutputTemplate(value); err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			batchOptions.OutputTemplate = value
+		case jobsOption:
+			jobs, err := strconv.Atoi(value)
+			if err != nil || jobs < 1 {
+				return fmt.Errorf("invalid value for option [%s]: expected a positive integer, got %q", option, value)
+			}
+			batchOptions.Jobs = jobs
+		case summaryOption:
+			batchOptions.SummaryPath = value
+		case verboseFlag, verboseShortFlag:
+			cliOptions.Verbose = true
+			cliOptions.LogLevel = min(cliOptions.LogLevel, slog.LevelInfo)
+		case debugShortFlag:
+			cliOptions.Verbose = true
+			cliOptions.LogLevel = slog.LevelDebug
+		case quietFlag:
+			cliOptions.Quiet = true
+			cliOptions.LogLevel = slog.LevelError
+		case logFormatOption:
+			logFormat, err := ParseLogFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.LogFormat = logFormat
+		case verifySizesFlag:
+			cliOptions.RepackageOptions.VerifySizes = true
+		case warnCaseCollisionsFlag:
+			cliOptions.RepackageOptions.WarnCaseCollisions = true
+		case dedupeContentFlag:
+			cliOptions.RepackageOptions.DedupeContent = true
+		case canonicalOption:
+			canonicalRule, err := repackage.ParseCanonicalRule(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.RepackageOptions.CanonicalRule = canonicalRule
+		case hashOption:
+			hashAlgorithm, err := repackage.ParseHashAlgorithm(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.RepackageOptions.HashAlgorithm = hashAlgorithm
+		case sumsOption:
+			cliOptions.SumsPath = value
+		case embedManifestFlag:
+			cliOptions.RepackageOptions.EmbedManifest = true
+		case manifestFormatOption:
+			manifestFormat, err := repackage.ParseManifestFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			cliOptions.RepackageOptions.ManifestFormat = manifestFormat
+		default:
+			isNameOption, err := parseNameOption(option, value, &cliOptions.RepackageOptions)
+			if err != nil {
+				return err
+			}
+			if !isNameOption {
+				return unknownOption(option, usage)
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if cliOptions.Quiet && cliOptions.Verbose {
+		return nil, fmt.Errorf("option [%s] cannot be combined with [%s], [%s] or [%s]",
+			quietFlag, verboseShortFlag, verboseFlag, debugShortFlag)
+	}
+
+	if cliOptions.OutputFormat == OutputJSON && cliOptions.ValidateOptions.ReportPath == validate.StdoutReportPath {
+		return nil, fmt.Errorf("options [%s %s] and [%s %s] cannot both write to the standard output",
+			outputOption, OutputJSON, reportOption, validate.StdoutReportPath)
+	}
+
+	// Updates read the JSON report of the previous run, so each run must write one to a file.
+	if cliOptions.Update && (cliOptions.ValidateOptions.ReportPath == validate.StdoutReportPath ||
+		cliOptions.ValidateOptions.ReportFormat != "" && cliOptions.ValidateOptions.ReportFormat != validate.ReportJSON) {
+		return nil, fmt.Errorf("option [%s] requires a %s validation report written to a file", updateFlag, validate.ReportJSON)
+	}
+
+	// Signatures are made over the JSON report file, which verification reads back.
+	if cliOptions.SigningKeyPath != "" && (cliOptions.ValidateOptions.ReportPath == validate.StdoutReportPath ||
+		cliOptions.ValidateOptions.ReportFormat != "" && cliOptions.ValidateOptions.ReportFormat != validate.ReportJSON) {
+		return nil, fmt.Errorf("option [%s] requires a %s validation report written to a file", signKeyOption, validate.ReportJSON)
+	}
+
+	// Entries with forged checksums would pass as the signed ones.
+	if cliOptions.SigningKeyPath != "" && !cliOptions.RepackageOptions.HashAlgorithm.IsCollisionResistant() {
+		return nil, fmt.Errorf("option [%s] requires a collision resistant [%s]", signKeyOption, hashOption)
+	}
+
+	if isBatch {
+		if err := validateBatchOptions(cliOptions, batchOptions, positionalArguments); err != nil {
+			return nil, err
+		}
+		batchOptions.Inputs = positionalArguments
+		cliOptions.Batch = batchOptions
+		return cliOptions, nil
+	}
+
+	if batchOptions.OutputDirectory != "" || batchOptions.OutputTemplate != "" || batchOptions.SummaryPath != "" {
+		return nil, fmt.Errorf("options [%s], [%s] and [%s] require [%s]",
+			outputDirOption, outputTemplateOption, summaryOption, batchFlag)
+	}
+
+	if len(positionalArguments) != 2 {
+		return nil, fmt.Errorf("invalid number of arguments. Usage: %s", usage)
+	}
+
+	cliOptions.InputZipPath = positionalArguments[0]
+	cliOptions.OutputZipPath = positionalArguments[1]
+
 	return cliOptions, nil
 }
 
+// validateBatchOptions checks that batch mode is given inputs and a single way to name outputs.
+func validateBatchOptions(cliOptions *Config, batchOptions *BatchConfig, positionalArguments []string) error {
+	if len(positionalArguments) == 0 {
+		return fmt.Errorf("option [%s] requires at least one input. Usage: %s", batchFlag, usage)
+	}
+
+	if (batchOptions.OutputDirectory == "") == (batchOptions.OutputTemplate == "") {
+		return fmt.Errorf("option [%s] requires exactly one of [%s] and [%s]", batchFlag, outputDirOption, outputTemplateOption)
+	}
+
+	// Every archive has its own report, written next to its output.
+	if cliOptions.ValidateOptions.ReportPath != "" {
+		return fmt.Errorf("option [%s] cannot be combined with [%s]", batchFlag, reportOption)
+	}
+
+	if len(cliOptions.MergeZipPaths) > 0 {
+		return fmt.Errorf("option [%s] cannot be combined with [%s]", batchFlag, mergeOption)
+	}
+
+	if cliOptions.SumsPath != "" {
+		return fmt.Errorf("option [%s] cannot be combined with [%s]", batchFlag, sumsOption)
+	}
+
+	return nil
+}
+
+// parseNameOption parses the options computing flattened names and the keys they are
+// deduplicated under, shared by repackaging and inspection. It reports whether the option is
+// one of them.
+func parseNameOption(option, value string, repackageOptions *repackage.Options) (bool, error) {
+	switch option {
+	case caseInsensitiveFlag:
+		repackageOptions.CaseInsensitive = true
+	case keepDepthOption:
+		keepDepth, err := strconv.Atoi(value)
+		if err != nil || keepDepth < 1 {
+			return false, fmt.Errorf("invalid value for option [%s]: expected a positive integer, got %q", option, value)
+		}
+		repackageOptions.KeepDepth = keepDepth
+	case stripPrefixOption:
+		repackageOptions.StripPrefix = value
+	case renameOption:
+		renameTemplate, err := repackage.ParseRenameTemplate(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value for option [%s]: %w", option, err)
+		}
+		repackageOptions.RenameTemplate = renameTemplate
+	case rewriteOption:
+		rewriteRule, err := repackage.ParseRewriteRule(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value for option [%s]: %w", option, err)
+		}
+		repackageOptions.RewriteRules = append(repackageOptions.RewriteRules, rewriteRule)
+	case namesOption:
+		namePolicy, err := repackage.ParseNamePolicy(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value for option [%s]: %w", option, err)
+		}
+		repackageOptions.NamePolicy = namePolicy
+	case normalizeOption:
+		normalizationForm, err := repackage.ParseNormalizationForm(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value for option [%s]: %w", option, err)
+		}
+		repackageOptions.Normalization = normalizationForm
+	case legacyEncodingOption:
+		legacyEncoding, err := repackage.ParseLegacyEncoding(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid value for option [%s]: %w", option, err)
+		}
+		repackageOptions.LegacyEncoding = legacyEncoding
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// scanArguments calls handleOption for every option of a command line, in order, and returns its
+// positional arguments. Options for which takesValue is true accept both "--option value" and
+// "--option=value", and other options cannot be given a value. Errors mention the usage of the
+// command, and errors of handleOption are returned as is.
+func scanArguments(arguments []string, takesValue func(option string) bool, commandUsage string,
+	handleOption func(option, value string) error) ([]string, error) {
+	var positionalArguments []string
+
+	for index := 0; index < len(arguments); index++ {
+		argument := arguments[index]
+		if !strings.HasPrefix(argument, "-") {
+			positionalArguments = append(positionalArguments, argument)
+			continue
+		}
+
+		option, value, hasValue := strings.Cut(argument, "=")
+		if !hasValue && takesValue(option) {
+			if index+1 == len(arguments) {
+				return nil, fmt.Errorf("option [%s] requires a value. Usage: %s", option, commandUsage)
+			}
+			index++
+			value, hasValue = arguments[index], true
+		}
+		if hasValue && !takesValue(option) {
+			return nil, unknownOption(argument, commandUsage)
+		}
+
+		if err := handleOption(option, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return positionalArguments, nil
+}
+
+// unknownOption returns the error reporting an option that a command does not accept.
+func unknownOption(option, commandUsage string) error {
+	return fmt.Errorf("unknown option [%q]. Usage: %s", option, commandUsage)
+}
+
+// takesValue reports whether an option expects a value.
+func takesValue(option string) bool {
+	switch option {
+	case namesOption, normalizeOption, legacyEncodingOption, canonicalOption, keepDepthOption, stripPrefixOption,
+		renameOption, rewriteOption, manifestFormatOption, reportOption, reportFormatOption, outputOption, logFormatOption, mergeOption,
+		outputDirOption, outputTemplateOption, jobsOption, summaryOption, hashOption, sumsOption, signKeyOption:
+		return true
+	default:
+		return false
+	}
+}
+
 // validateInputFile checks that input exists, is readable, and is a valid ZIP file.
 func validateInputFile(inputPath string) error {
 	inputFileInfo, err := os.Stat(inputPath)
@@ -139,3 +732,22 @@ func validateDistinctPaths(inputPath, outputPath string) error {
 
 	return nil
 }
+
+// validateDistinctInputs ensures no archive is given more than once among the merged inputs,
+// which would only duplicate all of its entries.
+func validateDistinctInputs(inputPaths []string) error {
+	seenPaths := make(map[string]bool, len(inputPaths))
+	for _, inputPath := range inputPaths {
+		absoluteInputPath, err := filepath.Abs(inputPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve absolute input path: %w", err)
+		}
+
+		if seenPaths[absoluteInputPath] {
+			return fmt.Errorf("input zip file is given more than once: %s", inputPath)
+		}
+		seenPaths[absoluteInputPath] = true
+	}
+
+	return nil
+}
@@ -2,11 +2,18 @@ package args
 
 import (
 	"archive/zip"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/validate"
 )
 
 func TestParse(t *testing.T) {
@@ -29,6 +36,8 @@ func TestParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, config)
 		assert.Contains(t, err.Error(), "invalid number of arguments")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
 	})
 
 	t.Run("Returns error with too many arguments", func(t *testing.T) {
@@ -49,6 +58,8 @@ func TestParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, config)
 		assert.Contains(t, err.Error(), "unknown option")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
 	})
 
 	t.Run("Returns error when input file validation fails", func(t *testing.T) {
@@ -60,6 +71,8 @@ func TestParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, config)
 		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
 	})
 
 	t.Run("Returns error when output directory validation fails", func(t *testing.T) {
@@ -72,6 +85,8 @@ func TestParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, config)
 		assert.Contains(t, err.Error(), "output directory does not exist")
+		var typedErr *OutputError
+		assert.ErrorAs(t, err, &typedErr)
 	})
 
 	t.Run("Returns error when distinct paths validation fails", func(t *testing.T) {
@@ -82,6 +97,8 @@ func TestParse(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, config)
 		assert.Contains(t, err.Error(), "cannot be the same file")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
 	})
 
 	t.Run("Successfully parses without validate flag", func(t *testing.T) {
@@ -109,6 +126,557 @@ func TestParse(t *testing.T) {
 		assert.Equal(t, outputPath, config.OutputZipPath)
 		assert.True(t, config.Validate)
 	})
+
+	t.Run("Successfully parses with verify sizes flag", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--verify-sizes", "--validate"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Validate)
+		assert.True(t, config.RepackageOptions.VerifySizes)
+		assert.False(t, config.Verbose)
+	})
+
+	t.Run("Successfully parses verbose flags", func(t *testing.T) {
+		for _, flag := range []string{"-v", "--verbose"} {
+			os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), flag}
+
+			config, err := Parse()
+
+			assert.NoError(t, err)
+			assert.NotNil(t, config)
+			assert.True(t, config.Verbose)
+			assert.Equal(t, slog.LevelInfo, config.LogLevel)
+		}
+	})
+
+	t.Run("Successfully parses log options", func(t *testing.T) {
+		for _, testCase := range []struct {
+			arguments     []string
+			expectedLevel slog.Level
+		}{
+			{nil, slog.LevelWarn},
+			{[]string{"-vv"}, slog.LevelDebug},
+			{[]string{"-vv", "-v"}, slog.LevelDebug},
+			{[]string{"--quiet"}, slog.LevelError},
+		} {
+			os.Args = append([]string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--log-format=json"},
+				testCase.arguments...)
+
+			config, err := Parse()
+
+			assert.NoError(t, err)
+			assert.NotNil(t, config)
+			assert.Equal(t, testCase.expectedLevel, config.LogLevel, "Log level with %v", testCase.arguments)
+			assert.Equal(t, LogJSON, config.LogFormat)
+		}
+	})
+
+	t.Run("Returns error when quiet and verbose flags are combined", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--quiet", "-v"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "cannot be combined")
+	})
+
+	t.Run("Returns error with unknown log format", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--log-format", "xml"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), `unknown log format "xml"`)
+	})
+
+	t.Run("Returns error when an option value is missing", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "out.zip"), "--names"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "requires a value")
+	})
+
+	t.Run("Returns error with unknown name policy", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "out.zip"), "--names=dos"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown name policy")
+	})
+
+	t.Run("Returns error when a flag is given a value", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "out.zip"), "--validate=yes"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown option")
+	})
+
+	t.Run("Successfully parses name policy", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", "--names", "windows", validZipPath, outputPath}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, validZipPath, config.InputZipPath)
+		assert.Equal(t, outputPath, config.OutputZipPath)
+		assert.Equal(t, repackage.NamePolicyWindows, config.RepackageOptions.NamePolicy)
+	})
+
+	t.Run("Successfully parses case collision flags", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--case-insensitive", "--warn-case-collisions"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.RepackageOptions.CaseInsensitive)
+		assert.True(t, config.RepackageOptions.WarnCaseCollisions)
+	})
+
+	t.Run("Successfully parses name encoding options", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--normalize=nfc", "--legacy-encoding", "shift-jis"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, repackage.NormalizationNFC, config.RepackageOptions.Normalization)
+		assert.Equal(t, repackage.LegacyEncodingShiftJIS, config.RepackageOptions.LegacyEncoding)
+	})
+
+	t.Run("Successfully parses hash algorithm", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--hash", "blake2b"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, repackage.HashBLAKE2b, config.RepackageOptions.HashAlgorithm)
+	})
+
+	t.Run("Returns error with unknown hash algorithm", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--hash=md5"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), `unknown hash algorithm "md5"`)
+	})
+
+	t.Run("Successfully parses checksum file path", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		sumsPath := filepath.Join(tmpDir, "SHA256SUMS")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--sums", sumsPath}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, sumsPath, config.SumsPath)
+		assert.False(t, config.Validate)
+	})
+
+	t.Run("Successfully parses content deduplication options", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--dedupe-content", "--canonical", "shortest"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.RepackageOptions.DedupeContent)
+		assert.Equal(t, repackage.CanonicalShortest, config.RepackageOptions.CanonicalRule)
+	})
+
+	t.Run("Returns error with invalid keep depth", func(t *testing.T) {
+		for _, value := range []string{"0", "-1", "two"} {
+			os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "out.zip"), "--keep-depth", value}
+
+			config, err := Parse()
+
+			assert.Error(t, err)
+			assert.Nil(t, config)
+			assert.Contains(t, err.Error(), "expected a positive integer")
+		}
+	})
+
+	t.Run("Successfully parses partial flattening options", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--keep-depth", "2", "--strip-prefix=bundle/assets"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, 2, config.RepackageOptions.KeepDepth)
+		assert.Equal(t, "bundle/assets", config.RepackageOptions.StripPrefix)
+	})
+
+	t.Run("Returns error with invalid rename template", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "out.zip"), "--rename", "{unknown}"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown placeholder")
+	})
+
+	t.Run("Successfully parses rename options", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--rename", "{sha8}-{base}",
+			"--rewrite", `^v\d+/=>`, `--rewrite=\.jpeg$=>.jpg`}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, "{sha8}-{base}", config.RepackageOptions.RenameTemplate.String())
+		assert.Len(t, config.RepackageOptions.RewriteRules, 2)
+		assert.Equal(t, ".jpg", config.RepackageOptions.RewriteRules[1].Replacement)
+	})
+
+	t.Run("Successfully parses manifest options", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--embed-manifest", "--manifest-format", "csv"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.RepackageOptions.EmbedManifest)
+		assert.Equal(t, repackage.ManifestCSV, config.RepackageOptions.ManifestFormat)
+	})
+
+	t.Run("Returns error with unknown report format", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--report-format", "html"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), `unknown report format "html"`)
+	})
+
+	t.Run("Successfully parses report options and enables validation", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--report", "-", "--report-format=junit"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Validate)
+		assert.Equal(t, validate.StdoutReportPath, config.ValidateOptions.ReportPath)
+		assert.Equal(t, validate.ReportJUnit, config.ValidateOptions.ReportFormat)
+	})
+
+	t.Run("Returns error when the signing key cannot be read", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--sign-key", filepath.Join(tmpDir, "missing.pem")}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "failed to read key file")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when a signed report isn't a JSON file", func(t *testing.T) {
+		for _, reportArguments := range [][]string{{"--report", "-"}, {"--report-format", "markdown"}} {
+			os.Args = append([]string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--sign-key", "key.pem"}, reportArguments...)
+
+			config, err := Parse()
+
+			assert.Error(t, err)
+			assert.Nil(t, config)
+			assert.Contains(t, err.Error(), "option [--sign-key] requires a json validation report written to a file")
+		}
+	})
+
+	t.Run("Returns error when a signed report uses CRC-32C checksums", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--sign-key", "key.pem", "--hash", "crc32"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "option [--sign-key] requires a collision resistant [--hash]")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully reads the signing key and enables validation", func(t *testing.T) {
+		_, signingKey, err := ed25519.GenerateKey(nil)
+		assert.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+		assert.NoError(t, err)
+		keyPath := filepath.Join(tmpDir, "key.pem")
+		assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--sign-key=" + keyPath}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Validate)
+		assert.Equal(t, keyPath, config.SigningKeyPath)
+		assert.Equal(t, signingKey, config.ValidateOptions.SigningKey)
+	})
+
+	t.Run("Successfully parses input validation flag and enables validation", func(t *testing.T) {
+		outputPath := filepath.Join(tmpDir, "output.zip")
+		os.Args = []string{"rezip", validZipPath, outputPath, "--validate-input"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Validate)
+		assert.True(t, config.ValidateOptions.VerifyInput)
+	})
+
+	t.Run("Returns error with unknown output format", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--output", "yaml"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), `unknown output format "yaml"`)
+	})
+
+	t.Run("Returns error when the result and the report both go to the standard output", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--output=json", "--report", "-"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "cannot both write to the standard output")
+		var usageErr *UsageError
+		assert.ErrorAs(t, err, &usageErr)
+	})
+
+	t.Run("Successfully parses output format", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--output", "json"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, OutputJSON, config.OutputFormat)
+	})
+
+	t.Run("Returns error when a merged archive is invalid", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--merge", filepath.Join(tmpDir, "missing.zip")}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var inputErr *InputError
+		assert.ErrorAs(t, err, &inputErr)
+	})
+
+	t.Run("Returns error when an archive is merged twice", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--merge", validZipPath}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file is given more than once")
+		var usageErr *UsageError
+		assert.ErrorAs(t, err, &usageErr)
+	})
+
+	t.Run("Returns error when batch mode merges archives", func(t *testing.T) {
+		os.Args = []string{"rezip", "--batch", tmpDir, "--output-dir", tmpDir, "--merge", validZipPath}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "cannot be combined with [--merge]")
+	})
+
+	t.Run("Returns error when batch mode writes a checksum file", func(t *testing.T) {
+		os.Args = []string{"rezip", "--batch", tmpDir, "--output-dir", tmpDir, "--sums", filepath.Join(tmpDir, "SHA256SUMS")}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "cannot be combined with [--sums]")
+	})
+
+	t.Run("Successfully parses merged archives", func(t *testing.T) {
+		otherZipPath := filepath.Join(tmpDir, "other.zip")
+		createValidZip(t, otherZipPath)
+		thirdZipPath := filepath.Join(tmpDir, "third.zip")
+		createValidZip(t, thirdZipPath)
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--merge", otherZipPath, "--merge=" + thirdZipPath}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, []string{otherZipPath, thirdZipPath}, config.MergeZipPaths)
+		assert.Equal(t, []string{validZipPath, otherZipPath, thirdZipPath}, config.InputZipPaths())
+	})
+
+	t.Run("Returns error when an update doesn't write a JSON report file", func(t *testing.T) {
+		for _, reportArguments := range [][]string{{"--report", "-"}, {"--report-format", "csv"}} {
+			os.Args = append([]string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--update"}, reportArguments...)
+
+			config, err := Parse()
+
+			assert.Error(t, err)
+			assert.Nil(t, config)
+			assert.Contains(t, err.Error(), "requires a json validation report written to a file")
+		}
+	})
+
+	t.Run("Successfully parses update flag and enables validation", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--update", "--report-format", "json"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Update)
+		assert.True(t, config.Validate)
+	})
+
+	t.Run("Returns error when batch mode has no input", func(t *testing.T) {
+		os.Args = []string{"rezip", "--batch", "--output-dir", tmpDir}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "requires at least one input")
+		var usageErr *UsageError
+		assert.ErrorAs(t, err, &usageErr)
+	})
+
+	t.Run("Returns error when batch mode has no or both output options", func(t *testing.T) {
+		for _, arguments := range [][]string{
+			{"rezip", "--batch", validZipPath},
+			{"rezip", "--batch", validZipPath, "--output-dir", tmpDir, "--output-template", "{name}_flat.zip"},
+		} {
+			os.Args = arguments
+
+			config, err := Parse()
+
+			assert.Error(t, err)
+			assert.Nil(t, config)
+			assert.Contains(t, err.Error(), "requires exactly one of")
+		}
+	})
+
+	t.Run("Returns error when batch mode has a single report path", func(t *testing.T) {
+		os.Args = []string{"rezip", "--batch", validZipPath, "--output-dir", tmpDir, "--report", "report.json"}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "cannot be combined with [--report]")
+	})
+
+	t.Run("Returns error with invalid batch options", func(t *testing.T) {
+		for _, arguments := range [][]string{
+			{"rezip", "--batch", validZipPath, "--output-template", "out.zip"},
+			{"rezip", "--batch", validZipPath, "--output-dir", tmpDir, "--jobs", "0"},
+		} {
+			os.Args = arguments
+
+			config, err := Parse()
+
+			assert.Error(t, err)
+			assert.Nil(t, config)
+			assert.Contains(t, err.Error(), "invalid value for option")
+		}
+	})
+
+	t.Run("Returns error when batch options are given without batch mode", func(t *testing.T) {
+		os.Args = []string{"rezip", validZipPath, filepath.Join(tmpDir, "output.zip"), "--output-dir", tmpDir}
+
+		config, err := Parse()
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "require [--batch]")
+	})
+
+	t.Run("Successfully parses batch options without checking the archives", func(t *testing.T) {
+		missingZipPath := filepath.Join(tmpDir, "missing.zip")
+		os.Args = []string{"rezip", "--batch", validZipPath, missingZipPath, filepath.Join(tmpDir, "*.zip"),
+			"--output-template={dir}/{name}_flat.zip", "--jobs", "4", "--summary", "summary.json", "--validate"}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.True(t, config.Validate)
+		assert.Empty(t, config.InputZipPath)
+		assert.Equal(t, &BatchConfig{
+			Inputs:         []string{validZipPath, missingZipPath, filepath.Join(tmpDir, "*.zip")},
+			OutputTemplate: "{dir}/{name}_flat.zip",
+			Jobs:           4,
+			SummaryPath:    "summary.json",
+		}, config.Batch)
+	})
+
+	t.Run("Successfully defaults the number of batch jobs", func(t *testing.T) {
+		os.Args = []string{"rezip", "--batch", tmpDir, "--output-dir", tmpDir}
+
+		config, err := Parse()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config)
+		assert.Equal(t, runtime.NumCPU(), config.Batch.Jobs)
+	})
+}
+
+func TestRequestedOutputFormat(t *testing.T) {
+	t.Run("Successfully defaults to text", func(t *testing.T) {
+		assert.Equal(t, OutputText, RequestedOutputFormat(nil))
+		assert.Equal(t, OutputText, RequestedOutputFormat([]string{"--output"}))
+		assert.Equal(t, OutputText, RequestedOutputFormat([]string{"--output", "yaml"}))
+	})
+
+	t.Run("Successfully finds the output format of invalid arguments", func(t *testing.T) {
+		assert.Equal(t, OutputJSON, RequestedOutputFormat([]string{"--unknown", "--output", "json"}))
+		assert.Equal(t, OutputJSON, RequestedOutputFormat([]string{"--output=json", "only-one.zip"}))
+	})
 }
 
 func TestValidateInputFile(t *testing.T) {
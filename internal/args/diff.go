@@ -0,0 +1,115 @@
+package args
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"github.com/yash15112001/rezip/internal/validate"
+)
+
+const (
+	// DiffCommand is the first argument selecting the comparison of two archives.
+	DiffCommand = "diff"
+
+	// oldReportOption sets the path of the validation report of the old archive of a comparison.
+	oldReportOption = "--old-report"
+
+	// newReportOption sets the path of the validation report of the new archive of a comparison.
+	newReportOption = "--new-report"
+
+	// reportsFlag is the flag such that, if provided, the validation reports stored next to the
+	// compared archives are used when they exist.
+	reportsFlag = "--reports"
+
+	// diffUsage describes the command-line syntax of the diff command.
+	diffUsage = "rezip " + DiffCommand + " <old.zip> <new.zip> [" + oldReportOption + " path] [" + newReportOption + " path] [" +
+		reportsFlag + "] [" + outputOption + " text|json]"
+)
+
+// DiffConfig holds the parsed command-line arguments of the diff command.
+type DiffConfig struct {
+	OldZipPath string
+	NewZipPath string
+
+	// OldReportPath and NewReportPath are the JSON validation reports whose checksums are used
+	// instead of hashing the files of the archives. They are empty to hash every file.
+	OldReportPath string
+	NewReportPath string
+
+	// OutputFormat selects how the differences are printed. Defaults to OutputText.
+	OutputFormat OutputFormat
+}
+
+// ParseDiff validates the arguments following the diff command and returns a DiffConfig.
+// Errors are a *UsageError or an *InputError depending on the argument at fault.
+func ParseDiff(arguments []string) (*DiffConfig, error) {
+	diffOptions, useReports, err := parseDiffArguments(arguments)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	for _, zipPath := range []string{diffOptions.OldZipPath, diffOptions.NewZipPath} {
+		if err := validateInputFile(zipPath); err != nil {
+			return nil, &InputError{Err: err}
+		}
+	}
+
+	if useReports {
+		diffOptions.OldReportPath = cmp.Or(diffOptions.OldReportPath, storedReportPath(diffOptions.OldZipPath))
+		diffOptions.NewReportPath = cmp.Or(diffOptions.NewReportPath, storedReportPath(diffOptions.NewZipPath))
+	}
+
+	return diffOptions, nil
+}
+
+// parseDiffArguments parses the options and positional arguments of the diff command, and
+// reports whether the stored validation reports should be used.
+func parseDiffArguments(arguments []string) (*DiffConfig, bool, error) {
+	diffOptions := &DiffConfig{}
+	var useReports bool
+
+	positionalArguments, err := scanArguments(arguments, func(option string) bool {
+		return option == oldReportOption || option == newReportOption || option == outputOption
+	}, diffUsage, func(option, value string) error {
+		switch option {
+		case oldReportOption:
+			diffOptions.OldReportPath = value
+		case newReportOption:
+			diffOptions.NewReportPath = value
+		case reportsFlag:
+			useReports = true
+		case outputOption:
+			outputFormat, err := ParseOutputFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			diffOptions.OutputFormat = outputFormat
+		default:
+			return unknownOption(option, diffUsage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(positionalArguments) != 2 {
+		return nil, false, fmt.Errorf("invalid number of arguments. Usage: %s", diffUsage)
+	}
+
+	diffOptions.OldZipPath = positionalArguments[0]
+	diffOptions.NewZipPath = positionalArguments[1]
+
+	return diffOptions, useReports, nil
+}
+
+// storedReportPath returns the path of the JSON validation report stored next to an archive by
+// default, or an empty path when there is none.
+func storedReportPath(zipPath string) string {
+	reportPath := validate.ReportPath(zipPath, validate.Options{})
+	if _, err := os.Stat(reportPath); err != nil {
+		return ""
+	}
+	return reportPath
+}
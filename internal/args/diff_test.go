@@ -0,0 +1,80 @@
+package args
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	oldZipPath := filepath.Join(tmpDir, "old.zip")
+	createValidZip(t, oldZipPath)
+	newZipPath := filepath.Join(tmpDir, "new.zip")
+	createValidZip(t, newZipPath)
+
+	t.Run("Returns error with too few arguments", func(t *testing.T) {
+		config, err := ParseDiff([]string{oldZipPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "invalid number of arguments")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with unknown option", func(t *testing.T) {
+		config, err := ParseDiff([]string{oldZipPath, newZipPath, "--validate"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown option")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when an option value is missing", func(t *testing.T) {
+		config, err := ParseDiff([]string{oldZipPath, newZipPath, "--old-report"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "option [--old-report] requires a value")
+	})
+
+	t.Run("Returns error when an archive doesn't exist", func(t *testing.T) {
+		config, err := ParseDiff([]string{oldZipPath, filepath.Join(tmpDir, "missing.zip")})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully parses archives and options", func(t *testing.T) {
+		config, err := ParseDiff([]string{oldZipPath, newZipPath, "--old-report=old.json", "--new-report", "new.json", "--output", "json"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &DiffConfig{
+			OldZipPath:    oldZipPath,
+			NewZipPath:    newZipPath,
+			OldReportPath: "old.json",
+			NewReportPath: "new.json",
+			OutputFormat:  OutputJSON,
+		}, config)
+	})
+
+	t.Run("Successfully uses the stored reports that exist", func(t *testing.T) {
+		newReportPath := filepath.Join(tmpDir, "new_validation.json")
+		require.NoError(t, os.WriteFile(newReportPath, []byte("{}"), 0o644))
+
+		config, err := ParseDiff([]string{"--reports", oldZipPath, newZipPath})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &DiffConfig{OldZipPath: oldZipPath, NewZipPath: newZipPath, NewReportPath: newReportPath}, config)
+	})
+}
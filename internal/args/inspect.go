@@ -0,0 +1,81 @@
+package args
+
+import (
+	"fmt"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+const (
+	// InspectCommand is the first argument selecting the listing of the entries of an archive.
+	InspectCommand = "inspect"
+
+	// inspectUsage describes the command-line syntax of the inspect command.
+	inspectUsage = "rezip " + InspectCommand + " <input.zip> [" + outputOption + " text|json] [" +
+		namesOption + " posix|windows|portable] [" + caseInsensitiveFlag + "] [" +
+		normalizeOption + " none|nfc|nfd] [" + legacyEncodingOption + " none|cp437|shift-jis] [" +
+		keepDepthOption + " N] [" + stripPrefixOption + " path] [" + renameOption + " template] [" +
+		rewriteOption + " pattern=>replacement]..."
+)
+
+// InspectConfig holds the parsed command-line arguments of the inspect command.
+type InspectConfig struct {
+	InputZipPath string
+
+	// OutputFormat selects whether entries are printed as a table or as JSON. Defaults to OutputText.
+	OutputFormat OutputFormat
+
+	// RepackageOptions holds the options computing flattened names and duplicate groups.
+	RepackageOptions repackage.Options
+}
+
+// ParseInspect validates the arguments following the inspect command and returns an
+// InspectConfig. Errors are a *UsageError or an *InputError depending on the argument at fault.
+func ParseInspect(arguments []string) (*InspectConfig, error) {
+	inspectOptions, err := parseInspectArguments(arguments)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	if err := validateInputFile(inspectOptions.InputZipPath); err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	return inspectOptions, nil
+}
+
+// parseInspectArguments parses the options and positional arguments of the inspect command.
+func parseInspectArguments(arguments []string) (*InspectConfig, error) {
+	inspectOptions := &InspectConfig{}
+
+	positionalArguments, err := scanArguments(arguments, takesValue, inspectUsage, func(option, value string) error {
+		if option == outputOption {
+			outputFormat, err := ParseOutputFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			inspectOptions.OutputFormat = outputFormat
+			return nil
+		}
+
+		isNameOption, err := parseNameOption(option, value, &inspectOptions.RepackageOptions)
+		if err != nil {
+			return err
+		}
+		if !isNameOption {
+			return unknownOption(option, inspectUsage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(positionalArguments) != 1 {
+		return nil, fmt.Errorf("invalid number of arguments. Usage: %s", inspectUsage)
+	}
+
+	inspectOptions.InputZipPath = positionalArguments[0]
+
+	return inspectOptions, nil
+}
@@ -0,0 +1,69 @@
+package args
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestParseInspect(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	zipPath := filepath.Join(tmpDir, "input.zip")
+	createValidZip(t, zipPath)
+
+	t.Run("Returns error with too many arguments", func(t *testing.T) {
+		config, err := ParseInspect([]string{zipPath, zipPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "invalid number of arguments")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with an option that doesn't affect names", func(t *testing.T) {
+		config, err := ParseInspect([]string{zipPath, "--dedupe-content"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown option")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with an invalid name option", func(t *testing.T) {
+		config, err := ParseInspect([]string{zipPath, "--keep-depth", "0"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "invalid value for option [--keep-depth]")
+	})
+
+	t.Run("Returns error when the archive doesn't exist", func(t *testing.T) {
+		config, err := ParseInspect([]string{filepath.Join(tmpDir, "missing.zip")})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully parses the archive and name options", func(t *testing.T) {
+		config, err := ParseInspect([]string{"--output=json", zipPath, "--keep-depth", "2", "--case-insensitive", "--names", "windows"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &InspectConfig{
+			InputZipPath: zipPath,
+			OutputFormat: OutputJSON,
+			RepackageOptions: repackage.Options{
+				KeepDepth:       2,
+				CaseInsensitive: true,
+				NamePolicy:      repackage.NamePolicyWindows,
+			},
+		}, config)
+	})
+}
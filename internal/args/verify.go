@@ -0,0 +1,106 @@
+package args
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/yash15112001/rezip/internal/validate"
+)
+
+const (
+	// VerifyCommand is the first argument selecting the verification of an output archive against
+	// its signed validation report.
+	VerifyCommand = "verify"
+
+	// pubkeyOption sets the PEM file of the ed25519 public key checking the report signature.
+	pubkeyOption = "--pubkey"
+
+	// verifyUsage describes the command-line syntax of the verify command.
+	verifyUsage = "rezip " + VerifyCommand + " <output.zip> " + pubkeyOption + " key.pem [" + reportOption + " path] [" +
+		outputOption + " text|json]"
+)
+
+// VerifyConfig holds the parsed command-line arguments of the verify command.
+type VerifyConfig struct {
+	OutputZipPath string
+
+	// ReportPath is the JSON validation report of the archive, signed next to it. Defaults to the
+	// report stored next to the archive under its default name.
+	ReportPath string
+
+	// PublicKey checks the signature of the report.
+	PublicKey ed25519.PublicKey
+
+	// OutputFormat selects how the outcome of the verification is printed. Defaults to OutputText.
+	OutputFormat OutputFormat
+}
+
+// ParseVerify validates the arguments following the verify command and returns a VerifyConfig.
+// Errors are a *UsageError or an *InputError depending on the argument at fault. The report and
+// its signature are only read when verifying.
+func ParseVerify(arguments []string) (*VerifyConfig, error) {
+	verifyOptions, publicKeyPath, err := parseVerifyArguments(arguments)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	if err := validateInputFile(verifyOptions.OutputZipPath); err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	publicKey, err := validate.ReadPublicKey(publicKeyPath)
+	if err != nil {
+		return nil, &InputError{Err: err}
+	}
+	verifyOptions.PublicKey = publicKey
+
+	if verifyOptions.ReportPath == "" {
+		verifyOptions.ReportPath = validate.ReportPath(verifyOptions.OutputZipPath, validate.Options{})
+	}
+
+	return verifyOptions, nil
+}
+
+// parseVerifyArguments parses the options and positional arguments of the verify command, and
+// returns the path of the public key.
+func parseVerifyArguments(arguments []string) (*VerifyConfig, string, error) {
+	verifyOptions := &VerifyConfig{}
+	var publicKeyPath string
+
+	positionalArguments, err := scanArguments(arguments, func(option string) bool {
+		return option == pubkeyOption || option == reportOption || option == outputOption
+	}, verifyUsage, func(option, value string) error {
+		switch option {
+		case pubkeyOption:
+			publicKeyPath = value
+		case reportOption:
+			if value == validate.StdoutReportPath {
+				return fmt.Errorf("invalid value for option [%s]: the report must be read from a file", option)
+			}
+			verifyOptions.ReportPath = value
+		case outputOption:
+			outputFormat, err := ParseOutputFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			verifyOptions.OutputFormat = outputFormat
+		default:
+			return unknownOption(option, verifyUsage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(positionalArguments) != 1 {
+		return nil, "", fmt.Errorf("invalid number of arguments. Usage: %s", verifyUsage)
+	}
+	if publicKeyPath == "" {
+		return nil, "", fmt.Errorf("option [%s] is required. Usage: %s", pubkeyOption, verifyUsage)
+	}
+
+	verifyOptions.OutputZipPath = positionalArguments[0]
+
+	return verifyOptions, publicKeyPath, nil
+}
@@ -0,0 +1,94 @@
+package args
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	zipPath := filepath.Join(tmpDir, "output.zip")
+	createValidZip(t, zipPath)
+
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(tmpDir, "pub.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+
+	t.Run("Returns error without public key", func(t *testing.T) {
+		config, err := ParseVerify([]string{zipPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "option [--pubkey] is required")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with too many arguments", func(t *testing.T) {
+		config, err := ParseVerify([]string{zipPath, zipPath, "--pubkey", keyPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "invalid number of arguments")
+	})
+
+	t.Run("Returns error when the report is read from the standard output", func(t *testing.T) {
+		config, err := ParseVerify([]string{zipPath, "--pubkey", keyPath, "--report", "-"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "the report must be read from a file")
+	})
+
+	t.Run("Returns error when the public key cannot be read", func(t *testing.T) {
+		config, err := ParseVerify([]string{zipPath, "--pubkey", filepath.Join(tmpDir, "missing.pem")})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "failed to read key file")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when the archive doesn't exist", func(t *testing.T) {
+		config, err := ParseVerify([]string{filepath.Join(tmpDir, "missing.zip"), "--pubkey", keyPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully defaults to the report stored next to the archive", func(t *testing.T) {
+		config, err := ParseVerify([]string{zipPath, "--pubkey=" + keyPath})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &VerifyConfig{
+			OutputZipPath: zipPath,
+			ReportPath:    filepath.Join(tmpDir, "output_validation.json"),
+			PublicKey:     publicKey,
+		}, config)
+	})
+
+	t.Run("Successfully parses the report path and output format", func(t *testing.T) {
+		reportPath := filepath.Join(tmpDir, "signed.json")
+
+		config, err := ParseVerify([]string{"--output", "json", zipPath, "--pubkey", keyPath, "--report", reportPath})
+
+		assert.NoError(t, err)
+		assert.Equal(t, reportPath, config.ReportPath)
+		assert.Equal(t, OutputJSON, config.OutputFormat)
+	})
+}
@@ -0,0 +1,85 @@
+package args
+
+import (
+	"fmt"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+const (
+	// VerifySumsCommand is the first argument selecting the check of an archive against a
+	// checksum file.
+	VerifySumsCommand = "verify-sums"
+
+	// verifySumsUsage describes the command-line syntax of the verify-sums command.
+	verifySumsUsage = "rezip " + VerifySumsCommand + " <archive.zip> <SHA256SUMS> [" + hashOption + " sha256|sha512|blake2b|crc32] [" +
+		outputOption + " text|json]"
+)
+
+// VerifySumsConfig holds the parsed command-line arguments of the verify-sums command.
+type VerifySumsConfig struct {
+	ZipPath  string
+	SumsPath string
+
+	// HashAlgorithm is the algorithm of the checksums listed in the checksum file. It is empty
+	// unless given, and the algorithm is then inferred from the checksums.
+	HashAlgorithm repackage.HashAlgorithm
+
+	// OutputFormat selects how the outcome of the checks is printed. Defaults to OutputText.
+	OutputFormat OutputFormat
+}
+
+// ParseVerifySums validates the arguments following the verify-sums command and returns a
+// VerifySumsConfig. Errors are a *UsageError or an *InputError depending on the argument at
+// fault. The checksum file is only read when verifying.
+func ParseVerifySums(arguments []string) (*VerifySumsConfig, error) {
+	verifyOptions, err := parseVerifySumsArguments(arguments)
+	if err != nil {
+		return nil, &UsageError{Err: err}
+	}
+
+	if err := validateInputFile(verifyOptions.ZipPath); err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	return verifyOptions, nil
+}
+
+// parseVerifySumsArguments parses the options and positional arguments of the verify-sums command.
+func parseVerifySumsArguments(arguments []string) (*VerifySumsConfig, error) {
+	verifyOptions := &VerifySumsConfig{}
+
+	positionalArguments, err := scanArguments(arguments, func(option string) bool {
+		return option == hashOption || option == outputOption
+	}, verifySumsUsage, func(option, value string) error {
+		switch option {
+		case hashOption:
+			hashAlgorithm, err := repackage.ParseHashAlgorithm(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			verifyOptions.HashAlgorithm = hashAlgorithm
+		case outputOption:
+			outputFormat, err := ParseOutputFormat(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for option [%s]: %w", option, err)
+			}
+			verifyOptions.OutputFormat = outputFormat
+		default:
+			return unknownOption(option, verifySumsUsage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(positionalArguments) != 2 {
+		return nil, fmt.Errorf("invalid number of arguments. Usage: %s", verifySumsUsage)
+	}
+
+	verifyOptions.ZipPath = positionalArguments[0]
+	verifyOptions.SumsPath = positionalArguments[1]
+
+	return verifyOptions, nil
+}
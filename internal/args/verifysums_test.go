@@ -0,0 +1,67 @@
+package args
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestParseVerifySums(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	zipPath := filepath.Join(tmpDir, "archive.zip")
+	createValidZip(t, zipPath)
+	sumsPath := filepath.Join(tmpDir, "SHA256SUMS")
+
+	t.Run("Returns error without checksum file", func(t *testing.T) {
+		config, err := ParseVerifySums([]string{zipPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "invalid number of arguments")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with an unknown option", func(t *testing.T) {
+		config, err := ParseVerifySums([]string{zipPath, sumsPath, "--validate"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "unknown option")
+		var typedErr *UsageError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error with unknown hash algorithm", func(t *testing.T) {
+		config, err := ParseVerifySums([]string{zipPath, sumsPath, "--hash", "md5"})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), `unknown hash algorithm "md5"`)
+	})
+
+	t.Run("Returns error when the archive doesn't exist", func(t *testing.T) {
+		config, err := ParseVerifySums([]string{filepath.Join(tmpDir, "missing.zip"), sumsPath})
+
+		assert.Error(t, err)
+		assert.Nil(t, config)
+		assert.Contains(t, err.Error(), "input zip file does not exist")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully parses the archive, checksum file and options", func(t *testing.T) {
+		config, err := ParseVerifySums([]string{zipPath, "--hash=sha512", sumsPath, "--output", "json"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &VerifySumsConfig{
+			ZipPath:       zipPath,
+			SumsPath:      sumsPath,
+			HashAlgorithm: repackage.HashSHA512,
+			OutputFormat:  OutputJSON,
+		}, config)
+	})
+}
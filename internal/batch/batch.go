@@ -0,0 +1,163 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Placeholders of output templates.
+const (
+	// namePlaceholder is replaced by the base name of the input archive without its extension.
+	namePlaceholder = "{name}"
+
+	// dirPlaceholder is replaced by the directory of the input archive.
+	dirPlaceholder = "{dir}"
+)
+
+// archiveExtension is the extension of the archives found in input directories.
+const archiveExtension = ".zip"
+
+// Job is an archive of a batch along with the path of its repackaged output.
+type Job struct {
+	InputPath  string
+	OutputPath string
+}
+
+// ExpandInputs resolves the inputs of a batch into archive paths. Each input is either an
+// archive, a directory whose ".zip" files are processed (without descending into
+// subdirectories), or a glob pattern. The paths are sorted and listed once.
+func ExpandInputs(inputs []string) ([]string, error) {
+	var paths []string
+	for _, input := range inputs {
+		matches, err := expandInput(input)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no archive matches the inputs %s", strings.Join(inputs, ", "))
+	}
+
+	return paths, nil
+}
+
+// expandInput resolves a single input of a batch into archive paths.
+func expandInput(input string) ([]string, error) {
+	if inputInfo, err := os.Stat(input); err == nil && inputInfo.IsDir() {
+		entries, err := os.ReadDir(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list input directory: %w", err)
+		}
+
+		var paths []string
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), archiveExtension) {
+				paths = append(paths, filepath.Join(input, entry.Name()))
+			}
+		}
+		return paths, nil
+	}
+
+	// Inputs without glob metacharacters are kept as is, so that missing archives are reported
+	// as failed jobs instead of being silently ignored.
+	if !strings.ContainsAny(input, "*?[") {
+		return []string{input}, nil
+	}
+
+	paths, err := filepath.Glob(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input pattern %q: %w", input, err)
+	}
+	return paths, nil
+}
+
+// PlanJobs computes the output path of every archive, either as a file with the same name in
+// the output directory or by expanding the output template, and checks that no two archives
+// are written to the same path and that no archive is overwritten.
+func PlanJobs(inputPaths []string, outputDirectory, outputTemplate string) ([]Job, error) {
+	jobs := make([]Job, 0, len(inputPaths))
+	inputsByOutput := make(map[string]string, len(inputPaths))
+
+	for _, inputPath := range inputPaths {
+		outputPath := OutputPath(inputPath, outputDirectory, outputTemplate)
+
+		absoluteOutputPath, err := filepath.Abs(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve absolute output path: %w", err)
+		}
+		if otherInputPath, isTaken := inputsByOutput[absoluteOutputPath]; isTaken {
+			return nil, fmt.Errorf("archives %s and %s would both be written to %s", otherInputPath, inputPath, outputPath)
+		}
+		inputsByOutput[absoluteOutputPath] = inputPath
+
+		jobs = append(jobs, Job{InputPath: inputPath, OutputPath: outputPath})
+	}
+
+	for _, job := range jobs {
+		absoluteInputPath, err := filepath.Abs(job.InputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve absolute input path: %w", err)
+		}
+		if otherInputPath, isTaken := inputsByOutput[absoluteInputPath]; isTaken {
+			return nil, fmt.Errorf("archive %s would be overwritten by the output of %s", job.InputPath, otherInputPath)
+		}
+	}
+
+	return jobs, nil
+}
+
+// OutputPath returns the output path of an archive, either in the output directory under the
+// same name or, when the directory is empty, by expanding the output template.
+func OutputPath(inputPath, outputDirectory, outputTemplate string) string {
+	if outputDirectory != "" {
+		return filepath.Join(outputDirectory, filepath.Base(inputPath))
+	}
+
+	baseName := filepath.Base(inputPath)
+	replacer := strings.NewReplacer(
+		namePlaceholder, strings.TrimSuffix(baseName, filepath.Ext(baseName)),
+		dirPlaceholder, filepath.Dir(inputPath),
+	)
+	return filepath.Clean(replacer.Replace(outputTemplate))
+}
+
+// ValidateOutputTemplate checks that an output template gives every archive its own output.
+func ValidateOutputTemplate(outputTemplate string) error {
+	if !strings.Contains(outputTemplate, namePlaceholder) {
+		return fmt.Errorf("output template %q must contain %s", outputTemplate, namePlaceholder)
+	}
+	return nil
+}
+
+// Run processes the jobs with at most concurrency jobs at a time, and returns their results in
+// the order of the jobs. Every job is processed even when others fail, and process is expected
+// to return promptly once the context is cancelled.
+func Run[T any](ctx context.Context, jobs []Job, concurrency int, process func(context.Context, Job) T) []T {
+	results := make([]T, len(jobs))
+	slots := make(chan struct{}, max(concurrency, 1))
+
+	var waitGroup sync.WaitGroup
+	for index, job := range jobs {
+		slots <- struct{}{}
+		waitGroup.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				waitGroup.Done()
+			}()
+			results[index] = process(ctx, job)
+		}()
+	}
+	waitGroup.Wait()
+
+	return results
+}
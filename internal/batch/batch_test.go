@@ -0,0 +1,140 @@
+package batch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandInputs(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.zip", "b.ZIP", "notes.txt", "nested/c.zip"} {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+
+	t.Run("Returns error when no archive matches", func(t *testing.T) {
+		paths, err := ExpandInputs([]string{filepath.Join(tempDir, "*.tar")})
+
+		assert.Error(t, err)
+		assert.Nil(t, paths)
+		assert.Contains(t, err.Error(), "no archive matches the inputs")
+	})
+
+	t.Run("Returns error with an invalid pattern", func(t *testing.T) {
+		paths, err := ExpandInputs([]string{filepath.Join(tempDir, "[")})
+
+		assert.Error(t, err)
+		assert.Nil(t, paths)
+		assert.Contains(t, err.Error(), "invalid input pattern")
+	})
+
+	t.Run("Successfully expands directories without descending into subdirectories", func(t *testing.T) {
+		paths, err := ExpandInputs([]string{tempDir})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(tempDir, "a.zip"), filepath.Join(tempDir, "b.ZIP")}, paths)
+	})
+
+	t.Run("Successfully expands patterns and lists each archive once", func(t *testing.T) {
+		paths, err := ExpandInputs([]string{
+			filepath.Join(tempDir, "nested", "*.zip"),
+			filepath.Join(tempDir, "a.zip"),
+			filepath.Join(tempDir, "*.zip"),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(tempDir, "a.zip"), filepath.Join(tempDir, "nested", "c.zip")}, paths)
+	})
+
+	t.Run("Successfully keeps missing archives to report them", func(t *testing.T) {
+		missingPath := filepath.Join(tempDir, "missing.zip")
+
+		paths, err := ExpandInputs([]string{missingPath})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{missingPath}, paths)
+	})
+}
+
+func TestPlanJobs(t *testing.T) {
+	t.Run("Returns error when two archives have the same output", func(t *testing.T) {
+		jobs, err := PlanJobs([]string{filepath.Join("a", "x.zip"), filepath.Join("b", "x.zip")}, "out", "")
+
+		assert.Error(t, err)
+		assert.Nil(t, jobs)
+		assert.Contains(t, err.Error(), "would both be written to")
+	})
+
+	t.Run("Returns error when an output overwrites an archive of the batch", func(t *testing.T) {
+		jobs, err := PlanJobs([]string{"x.zip", "x_flat.zip"}, "", "{name}_flat.zip")
+
+		assert.Error(t, err)
+		assert.Nil(t, jobs)
+		assert.Contains(t, err.Error(), "would be overwritten by the output of x.zip")
+	})
+
+	t.Run("Successfully plans outputs in a directory", func(t *testing.T) {
+		jobs, err := PlanJobs([]string{filepath.Join("in", "x.zip"), filepath.Join("in", "y.zip")}, "out", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Job{
+			{InputPath: filepath.Join("in", "x.zip"), OutputPath: filepath.Join("out", "x.zip")},
+			{InputPath: filepath.Join("in", "y.zip"), OutputPath: filepath.Join("out", "y.zip")},
+		}, jobs)
+	})
+
+	t.Run("Successfully plans outputs from a template", func(t *testing.T) {
+		jobs, err := PlanJobs([]string{filepath.Join("in", "x.zip")}, "", "{dir}/flat/{name}.flat.zip")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Job{{InputPath: filepath.Join("in", "x.zip"), OutputPath: filepath.Join("in", "flat", "x.flat.zip")}}, jobs)
+	})
+}
+
+func TestValidateOutputTemplate(t *testing.T) {
+	t.Run("Returns error without the name placeholder", func(t *testing.T) {
+		err := ValidateOutputTemplate("{dir}/out.zip")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must contain {name}")
+	})
+
+	t.Run("Successfully accepts templates with the name placeholder", func(t *testing.T) {
+		assert.NoError(t, ValidateOutputTemplate("{dir}/{name}_flat.zip"))
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Successfully processes every job in order with limited concurrency", func(t *testing.T) {
+		jobs := make([]Job, 20)
+		for index := range jobs {
+			jobs[index] = Job{InputPath: filepath.Join("in", string(rune('a'+index))+".zip")}
+		}
+
+		var running, maxRunning atomic.Int32
+		results := Run(context.Background(), jobs, 3, func(ctx context.Context, job Job) string {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			return job.InputPath
+		})
+
+		require.Len(t, results, len(jobs))
+		for index, job := range jobs {
+			assert.Equal(t, job.InputPath, results[index])
+		}
+		assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+	})
+}
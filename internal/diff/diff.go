@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/validate"
+)
+
+// ChangeKind is the way a file differs between two archives.
+type ChangeKind string
+
+const (
+	// ChangeAdded marks a file only present in the new archive.
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved marks a file only present in the old archive.
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeModified marks a file present in both archives with different content.
+	ChangeModified ChangeKind = "modified"
+
+	// ChangeRenamed marks a file removed from the old archive whose content was added to the new
+	// archive under another name.
+	ChangeRenamed ChangeKind = "renamed"
+)
+
+// Entry describes a file of one of the compared archives.
+type Entry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Change describes a file that differs between the old and the new archive.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Old is the file in the old archive. It is nil for added files.
+	Old *Entry `json:"old,omitempty"`
+
+	// New is the file in the new archive. It is nil for removed files.
+	New *Entry `json:"new,omitempty"`
+}
+
+// Summary counts the files of the compared archives by kind of change.
+type Summary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Modified  int `json:"modified"`
+	Renamed   int `json:"renamed"`
+	Unchanged int `json:"unchanged"`
+
+	// Hashed is the number of files whose checksum was computed instead of being read from a
+	// validation report.
+	Hashed int `json:"hashed"`
+}
+
+// Result is the outcome of the comparison of two archives.
+type Result struct {
+	Summary Summary `json:"summary"`
+
+	// Changes lists the files that differ, sorted by name in the new archive, or in the old
+	// archive for removed files.
+	Changes []Change `json:"changes"`
+}
+
+// Options controls how two archives are compared.
+type Options struct {
+	// OldReportPath and NewReportPath are the paths of JSON validation reports of the archives.
+	// The checksums they list are used instead of hashing the files of the same name and size,
+	// unless they were computed with another algorithm than SHA-256.
+	OldReportPath string
+	NewReportPath string
+}
+
+// InputError reports that one of the compared archives or reports could not be read.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// Compare compares the files of two archives by name and SHA-256 checksum. Directories are
+// ignored. Failures are reported as an *InputError, and cancelling the context stops the
+// comparison between files with the context error.
+func Compare(ctx context.Context, oldZipPath, newZipPath string, options Options) (*Result, error) {
+	result := &Result{Changes: []Change{}}
+
+	oldEntries, err := readEntries(ctx, oldZipPath, options.OldReportPath, &result.Summary)
+	if err != nil {
+		return nil, err
+	}
+
+	newEntries, err := readEntries(ctx, newZipPath, options.NewReportPath, &result.Summary)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed, added []*Entry
+	for name, oldEntry := range oldEntries {
+		newEntry, exists := newEntries[name]
+		switch {
+		case !exists:
+			removed = append(removed, oldEntry)
+		case oldEntry.SHA256 != newEntry.SHA256:
+			result.Changes = append(result.Changes, Change{Kind: ChangeModified, Old: oldEntry, New: newEntry})
+			result.Summary.Modified++
+		default:
+			result.Summary.Unchanged++
+		}
+	}
+	for name, newEntry := range newEntries {
+		if _, exists := oldEntries[name]; !exists {
+			added = append(added, newEntry)
+		}
+	}
+
+	result.Changes = append(result.Changes, matchRenames(sortedByName(removed), sortedByName(added), &result.Summary)...)
+
+	slices.SortFunc(result.Changes, func(a, b Change) int {
+		return cmp.Compare(a.name(), b.name())
+	})
+
+	return result, nil
+}
+
+// matchRenames pairs each removed file with an added file with the same checksum, both given in
+// name order, and reports the other files as removed and added.
+func matchRenames(removed, added []*Entry, summary *Summary) []Change {
+	var changes []Change
+
+	addedByHash := make(map[string][]*Entry, len(added))
+	for _, entry := range added {
+		addedByHash[entry.SHA256] = append(addedByHash[entry.SHA256], entry)
+	}
+
+	renamedTo := make(map[*Entry]bool, len(added))
+	for _, oldEntry := range removed {
+		candidates := addedByHash[oldEntry.SHA256]
+		if len(candidates) == 0 {
+			changes = append(changes, Change{Kind: ChangeRemoved, Old: oldEntry})
+			summary.Removed++
+			continue
+		}
+
+		addedByHash[oldEntry.SHA256] = candidates[1:]
+		renamedTo[candidates[0]] = true
+		changes = append(changes, Change{Kind: ChangeRenamed, Old: oldEntry, New: candidates[0]})
+		summary.Renamed++
+	}
+
+	for _, newEntry := range added {
+		if !renamedTo[newEntry] {
+			changes = append(changes, Change{Kind: ChangeAdded, New: newEntry})
+			summary.Added++
+		}
+	}
+
+	return changes
+}
+
+// name returns the name under which a change is listed.
+func (c Change) name() string {
+	if c.New != nil {
+		return c.New.Name
+	}
+	return c.Old.Name
+}
+
+// readEntries reads the name, size and checksum of the files of an archive, taking checksums
+// from its validation report when one is given. A reported checksum is only used when the entry
+// still has the size and CRC-32 recorded by the report, so that an archive rewritten since its
+// report is hashed again.
+func readEntries(ctx context.Context, zipPath, reportPath string, summary *Summary) (map[string]*Entry, error) {
+	zipReader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, &InputError{Err: fmt.Errorf("failed to open zip %s: %w", zipPath, err)}
+	}
+	defer zipReader.Close()
+
+	var reportedFiles map[string]validate.ReportedFile
+	if reportPath != "" {
+		reportedFiles, err = validate.ReadReportFiles(reportPath)
+		if err != nil {
+			return nil, &InputError{Err: fmt.Errorf("failed to use report of %s: %w", zipPath, err)}
+		}
+	}
+
+	entries := make(map[string]*Entry, len(zipReader.File))
+	for _, file := range zipReader.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Only the first of several entries with the same name is extracted by most tools.
+		if _, exists := entries[file.Name]; exists || file.FileInfo().IsDir() {
+			continue
+		}
+
+		size := int64(file.UncompressedSize64)
+		reportedFile, isReported := reportedFiles[file.Name]
+		hash := reportedFile.Hash
+		if !isReported || reportedFile.Size != size || reportedFile.CRC32 != file.CRC32 ||
+			reportedFile.HashAlgorithm != repackage.HashSHA256 {
+			hash, err = repackage.HashOf(file, repackage.HashSHA256)
+			if err != nil {
+				return nil, &InputError{Err: fmt.Errorf("failed to hash file \"%s\" of %s: %w", file.Name, zipPath, err)}
+			}
+			summary.Hashed++
+		}
+
+		entries[file.Name] = &Entry{Name: file.Name, SHA256: hash.String(), Size: size}
+	}
+
+	return entries, nil
+}
+
+// sortedByName sorts entries by name.
+func sortedByName(entries []*Entry) []*Entry {
+	slices.SortFunc(entries, func(a, b *Entry) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return entries
+}
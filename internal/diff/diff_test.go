@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/validate"
+	"github.com/yash15112001/rezip/internal/ziptest"
+)
+
+func TestCompare(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldZipPath := filepath.Join(tempDir, "old.zip")
+	require.NoError(t, ziptest.Write(oldZipPath, [][2]string{
+		{"same.txt", "unchanged"},
+		{"edit.txt", "first version"},
+		{"gone.txt", "removed later"},
+		{"before.txt", "moved content"},
+		{"docs/", ""},
+	}))
+
+	newZipPath := filepath.Join(tempDir, "new.zip")
+	require.NoError(t, ziptest.Write(newZipPath, [][2]string{
+		{"same.txt", "unchanged"},
+		{"edit.txt", "second version"},
+		{"after.txt", "moved content"},
+		{"new.txt", "added later"},
+	}))
+
+	entry := func(name, content string) *Entry {
+		hash := sha256.Sum256([]byte(content))
+		return &Entry{Name: name, SHA256: hex.EncodeToString(hash[:]), Size: int64(len(content))}
+	}
+
+	t.Run("Returns error when an archive cannot be opened", func(t *testing.T) {
+		result, err := Compare(context.Background(), oldZipPath, filepath.Join(tempDir, "missing.zip"), Options{})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to open zip")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when a report cannot be read", func(t *testing.T) {
+		result, err := Compare(context.Background(), oldZipPath, newZipPath, Options{
+			OldReportPath: filepath.Join(tempDir, "missing.json"),
+		})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to use report of")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := Compare(ctx, oldZipPath, newZipPath, Options{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, result)
+	})
+
+	t.Run("Successfully reports added, removed, modified and renamed files", func(t *testing.T) {
+		result, err := Compare(context.Background(), oldZipPath, newZipPath, Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Result{
+			Summary: Summary{Added: 1, Removed: 1, Modified: 1, Renamed: 1, Unchanged: 1, Hashed: 8},
+			Changes: []Change{
+				{Kind: ChangeRenamed, Old: entry("before.txt", "moved content"), New: entry("after.txt", "moved content")},
+				{Kind: ChangeModified, Old: entry("edit.txt", "first version"), New: entry("edit.txt", "second version")},
+				{Kind: ChangeRemoved, Old: entry("gone.txt", "removed later")},
+				{Kind: ChangeAdded, New: entry("new.txt", "added later")},
+			},
+		}, result)
+	})
+
+	t.Run("Successfully reports identical archives", func(t *testing.T) {
+		result, err := Compare(context.Background(), oldZipPath, oldZipPath, Options{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Result{Summary: Summary{Unchanged: 4, Hashed: 8}, Changes: []Change{}}, result)
+	})
+
+	t.Run("Successfully takes checksums from a validation report", func(t *testing.T) {
+		flatZipPath := filepath.Join(tempDir, "flat.zip")
+		repackageResult, err := repackage.Run(context.Background(), []string{newZipPath}, flatZipPath, repackage.Options{})
+		require.NoError(t, err)
+		_, err = validate.Run(context.Background(), flatZipPath, repackageResult, validate.Options{})
+		require.NoError(t, err)
+
+		result, err := Compare(context.Background(), flatZipPath, newZipPath, Options{
+			OldReportPath: validate.ReportPath(flatZipPath, validate.Options{}),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Result{Summary: Summary{Unchanged: 4, Hashed: 4}, Changes: []Change{}}, result)
+	})
+
+	t.Run("Successfully hashes files rewritten since the validation report", func(t *testing.T) {
+		flatZipPath := filepath.Join(tempDir, "rewritten.zip")
+		repackageResult, err := repackage.Run(context.Background(), []string{newZipPath}, flatZipPath, repackage.Options{})
+		require.NoError(t, err)
+		_, err = validate.Run(context.Background(), flatZipPath, repackageResult, validate.Options{})
+		require.NoError(t, err)
+		require.NoError(t, ziptest.Write(flatZipPath, [][2]string{
+			{"same.txt", "UNCHANGED"},
+			{"edit.txt", "second version"},
+			{"after.txt", "moved content"},
+			{"new.txt", "added later"},
+		}))
+
+		result, err := Compare(context.Background(), flatZipPath, newZipPath, Options{
+			OldReportPath: validate.ReportPath(flatZipPath, validate.Options{}),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, Summary{Modified: 1, Unchanged: 3, Hashed: 5}, result.Summary)
+		assert.Equal(t, []Change{{Kind: ChangeModified, Old: entry("same.txt", "UNCHANGED"), New: entry("same.txt", "unchanged")}}, result.Changes)
+	})
+}
@@ -0,0 +1,26 @@
+package repackage
+
+// InputError reports that the input archive or one of its entries could not be read.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// ConflictError reports input entries that cannot be flattened into the same output archive,
+// such as files with the same name and size but different content.
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+func (e *ConflictError) Unwrap() error { return e.Err }
+
+// OutputError reports that the output archive could not be created or written.
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string { return e.Err.Error() }
+func (e *OutputError) Unwrap() error { return e.Err }
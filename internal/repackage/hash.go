@@ -0,0 +1,111 @@
+package repackage
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// HashAlgorithm selects the checksum computed for every file to deduplicate, record and
+// validate it.
+type HashAlgorithm string
+
+const (
+	// HashSHA256 computes SHA-256 checksums. It is the default.
+	HashSHA256 HashAlgorithm = "sha256"
+
+	// HashSHA512 computes SHA-512 checksums.
+	HashSHA512 HashAlgorithm = "sha512"
+
+	// HashBLAKE2b computes BLAKE2b-512 checksums, as printed by b2sum.
+	HashBLAKE2b HashAlgorithm = "blake2b"
+
+	// HashCRC32 computes CRC-32C (Castagnoli) checksums. They are much faster to compute but do
+	// not protect against collisions, so files with the same size and checksum are compared
+	// again with SHA-256 before one of them is dropped as a duplicate.
+	HashCRC32 HashAlgorithm = "crc32"
+)
+
+// crc32cTable is the CRC-32C table used by HashCRC32.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Digest is the checksum of a file computed with a HashAlgorithm. It holds the raw bytes of the
+// checksum, so that digests can be compared and used as map keys.
+type Digest string
+
+// ParseHashAlgorithm converts a command-line value into a HashAlgorithm.
+func ParseHashAlgorithm(value string) (HashAlgorithm, error) {
+	switch algorithm := HashAlgorithm(value); algorithm {
+	case HashSHA256, HashSHA512, HashBLAKE2b, HashCRC32:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q: expected %s, %s, %s or %s",
+			value, HashSHA256, HashSHA512, HashBLAKE2b, HashCRC32)
+	}
+}
+
+// OrDefault returns the algorithm, or HashSHA256 for the zero value.
+func (a HashAlgorithm) OrDefault() HashAlgorithm {
+	if a == "" {
+		return HashSHA256
+	}
+	return a
+}
+
+// New returns a hash computing checksums with the algorithm.
+func (a HashAlgorithm) New() hash.Hash {
+	switch a.OrDefault() {
+	case HashSHA512:
+		return sha512.New()
+	case HashBLAKE2b:
+		// New512 only fails for keys longer than 64 bytes.
+		blake2bHash, _ := blake2b.New512(nil)
+		return blake2bHash
+	case HashCRC32:
+		return crc32.New(crc32cTable)
+	default:
+		return sha256.New()
+	}
+}
+
+// IsCollisionResistant reports whether files with the same checksum can be assumed to have the
+// same content. Checksums of other algorithms collide by chance or by design.
+func (a HashAlgorithm) IsCollisionResistant() bool {
+	return a.OrDefault() != HashCRC32
+}
+
+// Size returns the length in bytes of the checksums of the algorithm.
+func (a HashAlgorithm) Size() int {
+	switch a.OrDefault() {
+	case HashSHA512:
+		return sha512.Size
+	case HashBLAKE2b:
+		return blake2b.Size
+	case HashCRC32:
+		return crc32.Size
+	default:
+		return sha256.Size
+	}
+}
+
+// ParseDigest decodes the hexadecimal checksum of a file computed with the algorithm.
+func (a HashAlgorithm) ParseDigest(value string) (Digest, error) {
+	sum, err := hex.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s checksum %q: %w", a.OrDefault(), value, err)
+	}
+	if len(sum) != a.Size() {
+		return "", fmt.Errorf("invalid %s checksum %q: expected %d bytes, got %d", a.OrDefault(), value, a.Size(), len(sum))
+	}
+	return Digest(sum), nil
+}
+
+// String returns the checksum in hexadecimal.
+func (d Digest) String() string {
+	return hex.EncodeToString([]byte(d))
+}
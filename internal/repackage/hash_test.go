@@ -0,0 +1,154 @@
+package repackage
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHashAlgorithm(t *testing.T) {
+	t.Run("Returns error for unknown algorithm", func(t *testing.T) {
+		_, err := ParseHashAlgorithm("md5")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown hash algorithm "md5"`)
+	})
+
+	t.Run("Successfully parses all algorithms", func(t *testing.T) {
+		for _, value := range []string{"sha256", "sha512", "blake2b", "crc32"} {
+			algorithm, err := ParseHashAlgorithm(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, HashAlgorithm(value), algorithm)
+		}
+	})
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	t.Run("Successfully computes checksums of every algorithm", func(t *testing.T) {
+		for algorithm, expected := range map[HashAlgorithm]string{
+			"":          "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			HashSHA256:  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			HashSHA512:  "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+			HashBLAKE2b: "e4cfa39a3d37be31c59609e807970799caa68a19bfaa15135f165085e01d41a65ba1e1b146aeb6bd0092b49eac214c103ccfa3a365954bbbe52f74a2b3620c94",
+			HashCRC32:   "9a71bb4c",
+		} {
+			hashCalculator := algorithm.New()
+			hashCalculator.Write([]byte("hello"))
+			digest := Digest(hashCalculator.Sum(nil))
+
+			assert.Equal(t, expected, digest.String(), "Checksum of %q", algorithm)
+			assert.Len(t, digest, algorithm.Size())
+		}
+	})
+
+	t.Run("Returns error when parsing a digest of another length", func(t *testing.T) {
+		_, err := HashCRC32.ParseDigest("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "expected 4 bytes, got 32")
+	})
+
+	t.Run("Returns error when parsing a digest that is not hexadecimal", func(t *testing.T) {
+		_, err := HashSHA256.ParseDigest("not hex")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid sha256 checksum")
+	})
+
+	t.Run("Successfully parses a digest", func(t *testing.T) {
+		digest, err := HashCRC32.ParseDigest("9a71bb4c")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Digest("\x9a\x71\xbb\x4c"), digest)
+	})
+}
+
+func TestRunWithHashAlgorithm(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.zip")
+	require.NoError(t, makeTestZip(inputPath, map[string]string{
+		"a/logo.png": "png bytes",
+		"b/logo.png": "png bytes",
+	}))
+
+	t.Run("Successfully records the algorithm and checksums of the files", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "output.zip")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{
+			HashAlgorithm: HashCRC32,
+			EmbedManifest: true,
+		})
+
+		assert.NoError(t, err)
+		require.Contains(t, result.Files, "logo.png")
+		hashCalculator := HashCRC32.New()
+		hashCalculator.Write([]byte("png bytes"))
+		assert.Equal(t, HashCRC32, result.Files["logo.png"].HashAlgorithm)
+		assert.Equal(t, Digest(hashCalculator.Sum(nil)), result.Files["logo.png"].Hash)
+
+		var document manifest
+		require.NoError(t, json.Unmarshal([]byte(readManifest(t, outputPath, "MANIFEST.json")), &document))
+		assert.Equal(t, HashCRC32, document.HashAlgorithm)
+		require.Len(t, document.Files, 1)
+		assert.Empty(t, document.Files[0].SHA256)
+		assert.Equal(t, Digest(hashCalculator.Sum(nil)).String(), document.Files[0].Hash)
+	})
+
+	// "content 1371838" and "content 2000402" have the same size and CRC-32C checksum ae8e20d8.
+	t.Run("Returns error when files of the same name only have colliding checksums", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "colliding_names_input.zip")
+		require.NoError(t, makeTestZip(inputPath, map[string]string{
+			"a/data.bin": "content 1371838",
+			"b/data.bin": "content 2000402",
+		}))
+
+		result, err := Run(context.Background(), []string{inputPath}, filepath.Join(tempDir, "colliding_names.zip"), Options{
+			HashAlgorithm: HashCRC32,
+		})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), `files with name "data.bin" have identical sizes but differing content`)
+		var typedErr *ConflictError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully keeps files whose checksums only collide when deduplicating content", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "colliding_content_input.zip")
+		require.NoError(t, makeTestZip(inputPath, map[string]string{
+			"a/first.bin":  "content 1371838",
+			"b/second.bin": "content 2000402",
+			"c/third.bin":  "content 2000402",
+		}))
+
+		result, err := Run(context.Background(), []string{inputPath}, filepath.Join(tempDir, "colliding_content.zip"), Options{
+			HashAlgorithm: HashCRC32,
+			DedupeContent: true,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Files, 2)
+		require.Contains(t, result.Files, "first.bin")
+		assert.Empty(t, result.Files["first.bin"].Aliases)
+		require.Contains(t, result.Files, "second.bin")
+		assert.Equal(t, []Alias{{Name: "third.bin", OriginalPath: "c/third.bin"}}, result.Files["second.bin"].Aliases)
+	})
+
+	t.Run("Successfully keeps SHA-256 names in rename templates", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{sha8}{ext}")
+		require.NoError(t, err)
+
+		result, err := Run(context.Background(), []string{inputPath}, filepath.Join(tempDir, "renamed.zip"), Options{
+			HashAlgorithm:  HashSHA512,
+			RenameTemplate: template,
+		})
+
+		assert.NoError(t, err)
+		assert.Contains(t, result.Files, sha256Digest("png bytes").String()[:8]+".png")
+	})
+}
@@ -0,0 +1,98 @@
+package repackage
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// HashCache memoizes the size and checksum of ZIP entries for each hash algorithm, so that each
+// entry is read at most once per run by deduplication, output and validation. It is not safe for
+// concurrent use.
+type HashCache struct {
+	measurements map[measurementKey]measurement
+	hits         int
+	misses       int
+}
+
+// HashCacheStats reports how often the checksum of an entry was served from a HashCache.
+type HashCacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// measurement is the actual size and checksum of a decompressed entry.
+type measurement struct {
+	size int64
+	hash Digest
+}
+
+// measurementKey identifies the measurement of an entry with a hash algorithm.
+type measurementKey struct {
+	file      *zip.File
+	algorithm HashAlgorithm
+}
+
+// NewHashCache creates an empty HashCache.
+func NewHashCache() *HashCache {
+	return &HashCache{measurements: make(map[measurementKey]measurement)}
+}
+
+// Sum returns the checksum of an entry computed with the algorithm, reading it only if it is not
+// cached yet.
+func (c *HashCache) Sum(file *zip.File, algorithm HashAlgorithm) (Digest, error) {
+	entryMeasurement, _, err := c.measure(file, algorithm, (*zip.File).Open)
+	return entryMeasurement.hash, err
+}
+
+// Measure returns the actual size and checksum of an entry computed with the algorithm, without
+// trusting the size declared in its header, reading it only if it is not cached yet.
+func (c *HashCache) Measure(file *zip.File, algorithm HashAlgorithm) (int64, Digest, error) {
+	entryMeasurement, _, err := c.measure(file, algorithm, openMeasured)
+	return entryMeasurement.size, entryMeasurement.hash, err
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *HashCache) Stats() HashCacheStats {
+	return HashCacheStats{Hits: c.hits, Misses: c.misses}
+}
+
+// measure returns the measurement of an entry, reading it with the given function if it is not
+// cached yet. It also reports whether the entry was read by this call.
+func (c *HashCache) measure(file *zip.File, algorithm HashAlgorithm, open func(*zip.File) (io.ReadCloser, error)) (measurement, bool, error) {
+	if cached, ok := c.lookup(file, algorithm); ok {
+		return cached, false, nil
+	}
+
+	reader, err := open(file)
+	if err != nil {
+		return measurement{}, false, err
+	}
+	defer reader.Close()
+
+	hashCalculator := algorithm.New()
+	actualSize, err := io.Copy(hashCalculator, reader)
+	if err != nil {
+		return measurement{}, false, err
+	}
+
+	entryMeasurement := measurement{size: actualSize, hash: Digest(hashCalculator.Sum(nil))}
+	c.store(file, algorithm, entryMeasurement)
+
+	return entryMeasurement, true, nil
+}
+
+// lookup returns the cached measurement of an entry with the algorithm, counting a hit or a miss.
+func (c *HashCache) lookup(file *zip.File, algorithm HashAlgorithm) (measurement, bool) {
+	cached, ok := c.measurements[measurementKey{file: file, algorithm: algorithm.OrDefault()}]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return cached, ok
+}
+
+// store caches the measurement of an entry with the algorithm.
+func (c *HashCache) store(file *zip.File, algorithm HashAlgorithm, entryMeasurement measurement) {
+	c.measurements[measurementKey{file: file, algorithm: algorithm.OrDefault()}] = entryMeasurement
+}
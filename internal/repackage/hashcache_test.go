@@ -0,0 +1,82 @@
+package repackage
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCache(t *testing.T) {
+	t.Run("Returns error when entry cannot be read", func(t *testing.T) {
+		hashCache := NewHashCache()
+		corruptedFile := makeCorruptedZipFile(t, "bad.txt", []byte("content"))
+
+		_, err := hashCache.Sum(corruptedFile, HashSHA256)
+
+		assert.Error(t, err)
+		assert.Equal(t, HashCacheStats{Hits: 0, Misses: 1}, hashCache.Stats())
+
+		// Failures are not cached.
+		_, err = hashCache.Sum(corruptedFile, HashSHA256)
+		assert.Error(t, err)
+		assert.Equal(t, HashCacheStats{Hits: 0, Misses: 2}, hashCache.Stats())
+	})
+
+	t.Run("Successfully reads each entry once", func(t *testing.T) {
+		hashCache := NewHashCache()
+		file1 := createTestZipFile("file1.txt", "content1")
+		file2 := createTestZipFile("file2.txt", "content2")
+
+		for range 3 {
+			hash1, err := hashCache.Sum(file1, HashSHA256)
+			assert.NoError(t, err)
+			assert.Equal(t, sha256Digest("content1"), hash1)
+
+			hash2, err := hashCache.Sum(file2, HashSHA256)
+			assert.NoError(t, err)
+			assert.Equal(t, sha256Digest("content2"), hash2)
+		}
+
+		assert.Equal(t, HashCacheStats{Hits: 4, Misses: 2}, hashCache.Stats())
+	})
+
+	t.Run("Successfully measures entries with wrong declared sizes", func(t *testing.T) {
+		hashCache := NewHashCache()
+		file := createTestZipFileWithDeclaredSize(t, "file.txt", "actual content", 3)
+
+		size, hash, err := hashCache.Measure(file, HashSHA256)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len("actual content")), size)
+		assert.Equal(t, sha256Digest("actual content"), hash)
+
+		cachedHash, err := hashCache.Sum(file, HashSHA256)
+		assert.NoError(t, err)
+		assert.Equal(t, hash, cachedHash)
+		assert.Equal(t, HashCacheStats{Hits: 1, Misses: 1}, hashCache.Stats())
+	})
+
+	t.Run("Successfully caches each algorithm separately", func(t *testing.T) {
+		hashCache := NewHashCache()
+		file := createTestZipFile("file.txt", "content")
+
+		sha256Hash, err := hashCache.Sum(file, "")
+		assert.NoError(t, err)
+		crc32Hash, err := hashCache.Sum(file, HashCRC32)
+		assert.NoError(t, err)
+		cachedHash, err := hashCache.Sum(file, HashSHA256)
+		assert.NoError(t, err)
+
+		assert.Equal(t, sha256Digest("content"), sha256Hash)
+		assert.Len(t, crc32Hash, 4)
+		assert.Equal(t, sha256Hash, cachedHash)
+		assert.Equal(t, HashCacheStats{Hits: 1, Misses: 2}, hashCache.Stats())
+	})
+}
+
+// sha256Digest returns the SHA-256 digest of a content.
+func sha256Digest(content string) Digest {
+	sum := sha256.Sum256([]byte(content))
+	return Digest(sum[:])
+}
@@ -0,0 +1,112 @@
+package repackage
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// EntryInfo describes an entry of an input archive and how repackaging would treat it.
+type EntryInfo struct {
+	// Path is the full path of the entry in the archive.
+	Path string
+
+	CompressedSize   int64
+	UncompressedSize int64
+
+	// Method is the name of the compression method, such as "deflate".
+	Method string
+
+	Modified time.Time
+	Mode     fs.FileMode
+
+	// IsDir, IsSymlink and IsMetadata report the entries left out of the output: directories are
+	// removed by flattening, and symlinks and metadata files are skipped.
+	IsDir      bool
+	IsSymlink  bool
+	IsMetadata bool
+
+	// FlattenedName is the name of the entry in the output ZIP before deduplication. It is empty
+	// for the entries left out of the output.
+	FlattenedName string
+
+	// DuplicateGroup is the flattened name of the first entry deduplicated with this one, which
+	// identifies the group of entries of which only one is kept. It is empty when no other entry
+	// has the same flattened name.
+	DuplicateGroup string
+}
+
+// Inspect lists the entries of an input archive in archive order, along with their flattened
+// names and duplicate groups computed with the naming options. Nothing is written, and entries
+// are only read when the rename template needs their checksum. Failures are reported as an
+// *InputError, and cancelling the context stops the inspection between entries with the
+// context error.
+func Inspect(ctx context.Context, inputPath string, options Options) ([]EntryInfo, error) {
+	reader, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return nil, &InputError{Err: fmt.Errorf("failed to open input zip %s: %w", inputPath, err)}
+	}
+	defer reader.Close()
+
+	r := newRepackager(options)
+
+	entries := make([]EntryInfo, len(reader.File))
+
+	// Map from the key that identifies duplicates to the indexes of the entries sharing it.
+	groups := make(map[string][]int)
+
+	for index, file := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entries[index] = EntryInfo{
+			Path:             file.Name,
+			CompressedSize:   int64(file.CompressedSize64),
+			UncompressedSize: int64(file.UncompressedSize64),
+			Method:           methodName(file.Method),
+			Modified:         file.Modified,
+			Mode:             file.Mode(),
+			IsDir:            file.FileInfo().IsDir(),
+			IsSymlink:        isSymlink(file),
+			IsMetadata:       isMetadataFile(file.Name),
+		}
+		if entries[index].IsDir || entries[index].IsSymlink || entries[index].IsMetadata {
+			continue
+		}
+
+		flattenedName, err := r.flattenName(file, index)
+		if err != nil {
+			return nil, &InputError{Err: fmt.Errorf("failed flattening name of file \"%s\": %w", file.Name, err)}
+		}
+		entries[index].FlattenedName = flattenedName
+
+		duplicateKey := r.duplicateKey(flattenedName)
+		groups[duplicateKey] = append(groups[duplicateKey], index)
+	}
+
+	for _, indexes := range groups {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, index := range indexes {
+			entries[index].DuplicateGroup = entries[indexes[0]].FlattenedName
+		}
+	}
+
+	return entries, nil
+}
+
+// methodName returns the name of a ZIP compression method.
+func methodName(method uint16) string {
+	switch method {
+	case zip.Store:
+		return "store"
+	case zip.Deflate:
+		return "deflate"
+	default:
+		return fmt.Sprintf("method %d", method)
+	}
+}
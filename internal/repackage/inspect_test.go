@@ -0,0 +1,111 @@
+package repackage
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	tempDir := t.TempDir()
+
+	inputPath := filepath.Join(tempDir, "input.zip")
+	inputFile, err := os.Create(inputPath)
+	require.NoError(t, err)
+	zipWriter := zip.NewWriter(inputFile)
+	for _, header := range []*zip.FileHeader{
+		{Name: "docs/", Method: zip.Store},
+		{Name: "docs/README.md", Method: zip.Deflate},
+		{Name: "lib/readme.md", Method: zip.Store},
+		{Name: "lib/logo.png", Method: zip.Deflate},
+		{Name: "__MACOSX/._logo.png", Method: zip.Deflate},
+	} {
+		writer, err := zipWriter.CreateHeader(header)
+		require.NoError(t, err)
+		if !strings.HasSuffix(header.Name, "/") {
+			_, err = writer.Write([]byte(header.Name))
+			require.NoError(t, err)
+		}
+	}
+	symlinkHeader := &zip.FileHeader{Name: "lib/link.png", Method: zip.Store}
+	symlinkHeader.SetMode(os.ModeSymlink | 0o777)
+	writer, err := zipWriter.CreateHeader(symlinkHeader)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("logo.png"))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+	require.NoError(t, inputFile.Close())
+
+	t.Run("Returns error when the archive cannot be opened", func(t *testing.T) {
+		entries, err := Inspect(context.Background(), filepath.Join(tempDir, "missing.zip"), Options{})
+
+		assert.Error(t, err)
+		assert.Nil(t, entries)
+		assert.Contains(t, err.Error(), "failed to open input zip")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		entries, err := Inspect(ctx, inputPath, Options{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, entries)
+	})
+
+	t.Run("Successfully lists entries in archive order", func(t *testing.T) {
+		entries, err := Inspect(context.Background(), inputPath, Options{})
+
+		assert.NoError(t, err)
+		require.Len(t, entries, 6)
+
+		assert.Equal(t, "docs/", entries[0].Path)
+		assert.True(t, entries[0].IsDir)
+		assert.Empty(t, entries[0].FlattenedName)
+
+		assert.Equal(t, "docs/README.md", entries[1].Path)
+		assert.Equal(t, "deflate", entries[1].Method)
+		assert.Equal(t, int64(len("docs/README.md")), entries[1].UncompressedSize)
+		assert.Equal(t, "README.md", entries[1].FlattenedName)
+		assert.Empty(t, entries[1].DuplicateGroup)
+
+		assert.Equal(t, "store", entries[2].Method)
+		assert.Equal(t, entries[2].UncompressedSize, entries[2].CompressedSize)
+		assert.Equal(t, "readme.md", entries[2].FlattenedName)
+
+		assert.True(t, entries[4].IsMetadata)
+		assert.Empty(t, entries[4].FlattenedName)
+
+		assert.True(t, entries[5].IsSymlink)
+		assert.Equal(t, os.ModeSymlink, entries[5].Mode.Type())
+		assert.Empty(t, entries[5].FlattenedName)
+	})
+
+	t.Run("Successfully groups duplicates with the naming options", func(t *testing.T) {
+		entries, err := Inspect(context.Background(), inputPath, Options{CaseInsensitive: true})
+
+		assert.NoError(t, err)
+		require.Len(t, entries, 6)
+		assert.Equal(t, "README.md", entries[1].DuplicateGroup)
+		assert.Equal(t, "README.md", entries[2].DuplicateGroup)
+		assert.Empty(t, entries[3].DuplicateGroup)
+	})
+
+	t.Run("Successfully applies the keep depth to flattened names", func(t *testing.T) {
+		entries, err := Inspect(context.Background(), inputPath, Options{KeepDepth: 2})
+
+		assert.NoError(t, err)
+		require.Len(t, entries, 6)
+		assert.Equal(t, "docs/README.md", entries[1].FlattenedName)
+		assert.Equal(t, "lib/logo.png", entries[3].FlattenedName)
+	})
+}
@@ -0,0 +1,182 @@
+package repackage
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ManifestFormat is the format of the manifest embedded in the output ZIP.
+type ManifestFormat string
+
+const (
+	// ManifestJSON writes the manifest as a MANIFEST.json document.
+	ManifestJSON ManifestFormat = "json"
+
+	// ManifestCSV writes the manifest as a MANIFEST.csv table with one row per file.
+	ManifestCSV ManifestFormat = "csv"
+)
+
+// manifestBaseName is the name of the manifest entry without its extension.
+const manifestBaseName = "MANIFEST"
+
+// manifestListSeparator separates the items of list columns in a CSV manifest.
+const manifestListSeparator = ";"
+
+// manifest is the document embedded in the output ZIP to trace files back to the input archive.
+type manifest struct {
+	// HashAlgorithm names the algorithm of the file checksums. It is omitted for SHA-256, so
+	// that manifests read before the algorithm could be selected keep their format.
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm,omitempty"`
+
+	Files []manifestEntry `json:"files"`
+}
+
+// manifestEntry describes a single file of the output ZIP in the manifest. Its checksum is
+// written as SHA256 with the default algorithm, and as Hash with the others.
+type manifestEntry struct {
+	Name         string          `json:"name"`
+	OriginalPath string          `json:"original_path"`
+	SHA256       string          `json:"sha256,omitempty"`
+	Hash         string          `json:"hash,omitempty"`
+	Size         int64           `json:"size"`
+	Duplicates   []string        `json:"duplicates,omitempty"`
+	Aliases      []manifestAlias `json:"aliases,omitempty"`
+}
+
+// manifestAlias describes a file dropped because another file has identical content.
+type manifestAlias struct {
+	Name         string `json:"name"`
+	OriginalPath string `json:"original_path"`
+}
+
+// ParseManifestFormat converts a command-line value into a ManifestFormat.
+func ParseManifestFormat(value string) (ManifestFormat, error) {
+	switch format := ManifestFormat(value); format {
+	case ManifestJSON, ManifestCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown manifest format %q: expected %s or %s", value, ManifestJSON, ManifestCSV)
+	}
+}
+
+// manifestName returns the name of the manifest entry for the selected format.
+func (r *repackager) manifestName() string {
+	if r.options.ManifestFormat == ManifestCSV {
+		return manifestBaseName + ".csv"
+	}
+	return manifestBaseName + ".json"
+}
+
+// writeManifest adds the manifest entry describing the registry to the output ZIP.
+func (r *repackager) writeManifest(zipWriter *zip.Writer, outputFileRegistry map[string]FileInfo) error {
+	name := r.manifestName()
+	if _, isTaken := outputFileRegistry[name]; isTaken {
+		return &ConflictError{Err: fmt.Errorf("cannot embed manifest: the output zip already contains a file named \"%s\"", name)}
+	}
+
+	manifestWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("failed to create manifest in output zip: %w", err)}
+	}
+
+	document := buildManifest(outputFileRegistry, r.algorithm)
+	if r.options.ManifestFormat == ManifestCSV {
+		err = writeManifestCSV(manifestWriter, document)
+	} else {
+		err = writeManifestJSON(manifestWriter, document)
+	}
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("failed to write manifest in output zip: %w", err)}
+	}
+
+	return nil
+}
+
+// buildManifest converts the registry into a manifest sorted by file name.
+func buildManifest(outputFileRegistry map[string]FileInfo, algorithm HashAlgorithm) manifest {
+	names := make([]string, 0, len(outputFileRegistry))
+	for name := range outputFileRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	document := manifest{Files: make([]manifestEntry, 0, len(names))}
+	if algorithm != HashSHA256 {
+		document.HashAlgorithm = algorithm
+	}
+	for _, name := range names {
+		fileInfo := outputFileRegistry[name]
+
+		entry := manifestEntry{
+			Name:         name,
+			OriginalPath: fileInfo.OriginalPath,
+			Size:         fileInfo.Size,
+			Duplicates:   fileInfo.Duplicates,
+		}
+		if document.HashAlgorithm == "" {
+			entry.SHA256 = fileInfo.Hash.String()
+		} else {
+			entry.Hash = fileInfo.Hash.String()
+		}
+		for _, alias := range fileInfo.Aliases {
+			entry.Aliases = append(entry.Aliases, manifestAlias{Name: alias.Name, OriginalPath: alias.OriginalPath})
+		}
+
+		document.Files = append(document.Files, entry)
+	}
+
+	return document
+}
+
+func writeManifestJSON(writer io.Writer, document manifest) error {
+	jsonData, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(jsonData)
+	return err
+}
+
+// writeManifestCSV writes one row per file. The checksum column is named after the hash
+// algorithm. Duplicates and aliases are joined with semicolons, aliases being written as
+// name=original_path pairs.
+func writeManifestCSV(writer io.Writer, document manifest) error {
+	csvWriter := csv.NewWriter(writer)
+	hashColumn := string(document.HashAlgorithm.OrDefault())
+	if err := csvWriter.Write([]string{"name", "original_path", hashColumn, "size", "duplicates", "aliases"}); err != nil {
+		return err
+	}
+
+	for _, entry := range document.Files {
+		aliases := make([]string, 0, len(entry.Aliases))
+		for _, alias := range entry.Aliases {
+			aliases = append(aliases, alias.Name+"="+alias.OriginalPath)
+		}
+
+		record := []string{
+			entry.Name,
+			entry.OriginalPath,
+			cmp.Or(entry.SHA256, entry.Hash),
+			strconv.FormatInt(entry.Size, 10),
+			strings.Join(entry.Duplicates, manifestListSeparator),
+			strings.Join(aliases, manifestListSeparator),
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
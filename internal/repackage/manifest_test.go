@@ -0,0 +1,125 @@
+package repackage
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifestFormat(t *testing.T) {
+	t.Run("Returns error for unknown format", func(t *testing.T) {
+		_, err := ParseManifestFormat("xml")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown manifest format "xml"`)
+	})
+
+	t.Run("Successfully parses all formats", func(t *testing.T) {
+		for _, value := range []string{"json", "csv"} {
+			format, err := ParseManifestFormat(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ManifestFormat(value), format)
+		}
+	})
+}
+
+func TestEmbedManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.zip")
+	err := makeTestZip(inputPath, map[string]string{
+		"a/logo.png":      "png bytes",
+		"b/logo.png":      "png",
+		"c/logo-copy.png": "png bytes",
+		"docs/readme.txt": "readme",
+	})
+	require.NoError(t, err, "Failed to create test ZIP file")
+
+	logoHash := sha256.Sum256([]byte("png bytes"))
+	readmeHash := sha256.Sum256([]byte("readme"))
+
+	t.Run("Successfully embeds a JSON manifest", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "json_output.zip")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{EmbedManifest: true, DedupeContent: true, CanonicalRule: CanonicalShortest})
+
+		require.NoError(t, err)
+		assert.Equal(t, "MANIFEST.json", result.ManifestName)
+		assert.NotContains(t, result.Files, "MANIFEST.json", "Manifest should not be listed as a file")
+
+		var document manifest
+		require.NoError(t, json.Unmarshal([]byte(readManifest(t, outputPath, "MANIFEST.json")), &document))
+		assert.Equal(t, manifest{Files: []manifestEntry{
+			{
+				Name:         "logo.png",
+				OriginalPath: "a/logo.png",
+				SHA256:       hex.EncodeToString(logoHash[:]),
+				Size:         9,
+				Duplicates:   []string{"b/logo.png"},
+				Aliases:      []manifestAlias{{Name: "logo-copy.png", OriginalPath: "c/logo-copy.png"}},
+			},
+			{
+				Name:         "readme.txt",
+				OriginalPath: "docs/readme.txt",
+				SHA256:       hex.EncodeToString(readmeHash[:]),
+				Size:         6,
+			},
+		}}, document)
+	})
+
+	t.Run("Successfully embeds a CSV manifest", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "csv_output.zip")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{EmbedManifest: true, ManifestFormat: ManifestCSV})
+
+		require.NoError(t, err)
+		assert.Equal(t, "MANIFEST.csv", result.ManifestName)
+
+		records, err := csv.NewReader(strings.NewReader(readManifest(t, outputPath, "MANIFEST.csv"))).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"name", "original_path", "sha256", "size", "duplicates", "aliases"},
+			{"logo-copy.png", "c/logo-copy.png", hex.EncodeToString(logoHash[:]), "9", "", ""},
+			{"logo.png", "a/logo.png", hex.EncodeToString(logoHash[:]), "9", "b/logo.png", ""},
+			{"readme.txt", "docs/readme.txt", hex.EncodeToString(readmeHash[:]), "6", "", ""},
+		}, records)
+	})
+
+	t.Run("Returns error when a file has the name of the manifest", func(t *testing.T) {
+		conflictInputPath := filepath.Join(tempDir, "conflict_input.zip")
+		err := makeTestZip(conflictInputPath, map[string]string{"docs/MANIFEST.json": "{}"})
+		require.NoError(t, err)
+
+		_, err = Run(context.Background(), []string{conflictInputPath}, filepath.Join(tempDir, "conflict_output.zip"), Options{EmbedManifest: true})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `already contains a file named "MANIFEST.json"`)
+	})
+}
+
+// readManifest returns the content of the manifest entry of an output ZIP.
+func readManifest(t *testing.T, outputPath, name string) string {
+	zipReader, err := zip.OpenReader(outputPath)
+	require.NoError(t, err)
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		if file.Name == name {
+			content, err := readZipFileContent(file)
+			require.NoError(t, err)
+			return content
+		}
+	}
+
+	require.Failf(t, "manifest not found", "no entry named %s", name)
+	return ""
+}
@@ -0,0 +1,308 @@
+package repackage
+
+import (
+	"archive/zip"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NamePolicy selects the platforms on which flattened file names must be safe to extract.
+type NamePolicy string
+
+const (
+	// NamePolicyPOSIX only replaces characters that cannot appear in a POSIX file name.
+	NamePolicyPOSIX NamePolicy = "posix"
+
+	// NamePolicyWindows additionally replaces the characters and device names rejected by Windows.
+	NamePolicyWindows NamePolicy = "windows"
+
+	// NamePolicyPortable applies the Windows rules and restricts names to the POSIX portable
+	// filename character set (letters, digits, '.', '_' and '-').
+	NamePolicyPortable NamePolicy = "portable"
+)
+
+// NormalizationForm selects the Unicode normalization form applied to flattened names.
+type NormalizationForm string
+
+const (
+	// NormalizationNone keeps names as they are stored in the archive.
+	NormalizationNone NormalizationForm = "none"
+
+	// NormalizationNFC composes characters, as done by Linux and Windows archivers.
+	NormalizationNFC NormalizationForm = "nfc"
+
+	// NormalizationNFD decomposes characters, as done by macOS archivers.
+	NormalizationNFD NormalizationForm = "nfd"
+)
+
+// LegacyEncoding is the character encoding of entry names stored without the ZIP UTF-8 flag.
+type LegacyEncoding string
+
+const (
+	// LegacyEncodingNone keeps the bytes of such names unchanged.
+	LegacyEncodingNone LegacyEncoding = "none"
+
+	// LegacyEncodingCP437 decodes such names as IBM Code Page 437, the historical ZIP default.
+	LegacyEncodingCP437 LegacyEncoding = "cp437"
+
+	// LegacyEncodingShiftJIS decodes such names as Shift-JIS, used by Japanese Windows archivers.
+	LegacyEncodingShiftJIS LegacyEncoding = "shift-jis"
+)
+
+// replacementCharacter substitutes every character that is illegal under the selected policy.
+const replacementCharacter = '_'
+
+// windowsIllegalCharacters are the printable characters that Windows does not allow in file names.
+const windowsIllegalCharacters = `<>:"/\|?*`
+
+// windowsReservedNames are device names that Windows refuses to use as file names,
+// with or without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ParseNamePolicy converts a command-line value into a NamePolicy.
+func ParseNamePolicy(value string) (NamePolicy, error) {
+	switch policy := NamePolicy(value); policy {
+	case NamePolicyPOSIX, NamePolicyWindows, NamePolicyPortable:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown name policy %q: expected %s, %s or %s",
+			value, NamePolicyPOSIX, NamePolicyWindows, NamePolicyPortable)
+	}
+}
+
+// ParseNormalizationForm converts a command-line value into a NormalizationForm.
+func ParseNormalizationForm(value string) (NormalizationForm, error) {
+	switch form := NormalizationForm(value); form {
+	case NormalizationNone, NormalizationNFC, NormalizationNFD:
+		return form, nil
+	default:
+		return "", fmt.Errorf("unknown normalization form %q: expected %s, %s or %s",
+			value, NormalizationNone, NormalizationNFC, NormalizationNFD)
+	}
+}
+
+// ParseLegacyEncoding converts a command-line value into a LegacyEncoding.
+func ParseLegacyEncoding(value string) (LegacyEncoding, error) {
+	switch legacyEncoding := LegacyEncoding(value); legacyEncoding {
+	case LegacyEncodingNone, LegacyEncodingCP437, LegacyEncodingShiftJIS:
+		return legacyEncoding, nil
+	default:
+		return "", fmt.Errorf("unknown legacy encoding %q: expected %s, %s or %s",
+			value, LegacyEncodingNone, LegacyEncodingCP437, LegacyEncodingShiftJIS)
+	}
+}
+
+// flattenName reduces the path of a ZIP entry to its last path components, decoded, normalized,
+// rewritten and sanitized according to the options. Only the base name is kept unless
+// Options.KeepDepth is greater than one or Options.RenameTemplate is set. The index is the
+// position of the entry in the input archive.
+func (r *repackager) flattenName(file *zip.File, index int) (string, error) {
+	// Names must be decoded first, as the second byte of a Shift-JIS character may be a backslash.
+	entryName, err := decodeName(file, r.options.LegacyEncoding)
+	if err != nil {
+		return "", err
+	}
+
+	// The ZIP format mandates forward slashes, but archives created on Windows sometimes use
+	// backslashes, which filepath.Base does not treat as separators on other platforms.
+	normalizedName := normalizeUnicode(strings.ReplaceAll(entryName, `\`, "/"), r.options.Normalization)
+
+	components := splitPath(stripPrefix(rewritePath(normalizedName, r.options.RewriteRules), r.options.StripPrefix))
+	keptComponents := components[max(len(components)-max(r.options.KeepDepth, 1), 0):]
+
+	if r.options.RenameTemplate != nil {
+		renamedName, err := r.options.RenameTemplate.expand(renameContext{
+			components:     components,
+			keptComponents: keptComponents,
+			index:          index,
+			hash:           func() (Digest, error) { return r.sha256Of(file) },
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to expand rename template: %w", err)
+		}
+		keptComponents = splitPath(renamedName)
+	}
+
+	sanitizedComponents := make([]string, len(keptComponents))
+	for componentIndex, component := range keptComponents {
+		sanitizedComponents[componentIndex] = sanitizeName(component, r.options.NamePolicy)
+	}
+	if len(sanitizedComponents) == 0 {
+		return sanitizeName("", r.options.NamePolicy), nil
+	}
+
+	return strings.Join(sanitizedComponents, "/"), nil
+}
+
+// splitPath splits a slash-separated path into its components. Empty components, coming from
+// leading, trailing or repeated slashes, are dropped.
+func splitPath(entryPath string) []string {
+	return slices.DeleteFunc(strings.Split(entryPath, "/"), func(component string) bool {
+		return component == ""
+	})
+}
+
+// stripPrefix removes a leading directory from a slash-separated path. The path is returned
+// unchanged when it is not located under that directory.
+func stripPrefix(entryPath, prefix string) string {
+	prefix = strings.Trim(strings.ReplaceAll(prefix, `\`, "/"), "/")
+	if prefix == "" {
+		return entryPath
+	}
+
+	if relativePath, isUnderPrefix := strings.CutPrefix(strings.TrimLeft(entryPath, "/"), prefix+"/"); isUnderPrefix {
+		return relativePath
+	}
+	return entryPath
+}
+
+// decodeName returns the name of an entry as UTF-8. Names are only decoded when the entry is
+// not flagged as UTF-8 and its name is not plain ASCII, as reported by zip.File.NonUTF8.
+func decodeName(file *zip.File, legacyEncoding LegacyEncoding) (string, error) {
+	if !file.NonUTF8 {
+		return file.Name, nil
+	}
+
+	var decoder *encoding.Decoder
+	switch legacyEncoding {
+	case LegacyEncodingCP437:
+		decoder = charmap.CodePage437.NewDecoder()
+	case LegacyEncodingShiftJIS:
+		decoder = japanese.ShiftJIS.NewDecoder()
+	default:
+		return file.Name, nil
+	}
+
+	decodedName, err := decoder.String(file.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode name as %s: %w", legacyEncoding, err)
+	}
+	return decodedName, nil
+}
+
+// normalizeUnicode applies the given normalization form, so that visually identical names
+// created on different platforms are deduplicated together.
+func normalizeUnicode(name string, form NormalizationForm) string {
+	switch form {
+	case NormalizationNFC:
+		return norm.NFC.String(name)
+	case NormalizationNFD:
+		return norm.NFD.String(name)
+	default:
+		return name
+	}
+}
+
+// sanitizeName replaces the characters of a single path component that are illegal under the
+// given policy, and renames components that cannot be used as file names at all.
+// Bytes that are not valid UTF-8 are preserved unless the portable policy is selected.
+func sanitizeName(name string, policy NamePolicy) string {
+	var builder strings.Builder
+	builder.Grow(len(name))
+
+	for index := 0; index < len(name); {
+		character, width := utf8.DecodeRuneInString(name[index:])
+		if isIllegalCharacter(character, policy) {
+			builder.WriteRune(replacementCharacter)
+		} else {
+			builder.WriteString(name[index : index+width])
+		}
+		index += width
+	}
+
+	sanitizedName := builder.String()
+
+	if policy == NamePolicyWindows || policy == NamePolicyPortable {
+		// Windows silently drops trailing dots and spaces, so such names would not round-trip.
+		sanitizedName = strings.TrimRight(sanitizedName, ". ")
+
+		stem, _, _ := strings.Cut(sanitizedName, ".")
+		if windowsReservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+			sanitizedName = string(replacementCharacter) + sanitizedName
+		}
+	}
+
+	// Empty names and the special directory names cannot be extracted as regular files.
+	switch sanitizedName {
+	case "", ".", "..":
+		sanitizedName = strings.Repeat(string(replacementCharacter), max(len(sanitizedName), 1))
+	}
+
+	return sanitizedName
+}
+
+// isIllegalCharacter reports whether a decoded character may not appear in a file name under the
+// given policy. Bytes that are not valid UTF-8 are decoded as utf8.RuneError.
+func isIllegalCharacter(character rune, policy NamePolicy) bool {
+	if character == 0 || character == '/' {
+		return true
+	}
+
+	switch policy {
+	case NamePolicyWindows:
+		return character < 0x20 || strings.ContainsRune(windowsIllegalCharacters, character)
+	case NamePolicyPortable:
+		return !isPortableCharacter(character)
+	default:
+		return false
+	}
+}
+
+// isPortableCharacter reports whether a character belongs to the POSIX portable filename character set.
+func isPortableCharacter(character rune) bool {
+	return character >= 'a' && character <= 'z' ||
+		character >= 'A' && character <= 'Z' ||
+		character >= '0' && character <= '9' ||
+		character == '.' || character == '_' || character == '-'
+}
+
+// foldCase maps names that only differ by letter case to the same key.
+// Upper-casing first also folds special forms such as the Kelvin sign or the long s.
+func foldCase(name string) string {
+	return strings.ToLower(strings.ToUpper(name))
+}
+
+// annotateCaseCollisions records, for every output file, the other output names that only differ
+// from it by letter case, and returns the sorted groups of colliding names.
+func annotateCaseCollisions(files map[string]FileInfo) [][]string {
+	namesByKey := make(map[string][]string, len(files))
+	for name := range files {
+		key := foldCase(name)
+		namesByKey[key] = append(namesByKey[key], name)
+	}
+
+	var collisions [][]string
+	for _, names := range namesByKey {
+		if len(names) < 2 {
+			continue
+		}
+		slices.Sort(names)
+		collisions = append(collisions, names)
+
+		for _, name := range names {
+			fileInfo := files[name]
+			fileInfo.CaseCollisions = slices.DeleteFunc(slices.Clone(names), func(other string) bool {
+				return other == name
+			})
+			files[name] = fileInfo
+		}
+	}
+
+	slices.SortFunc(collisions, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return collisions
+}
@@ -0,0 +1,266 @@
+package repackage
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamePolicy(t *testing.T) {
+	t.Run("Returns error for unknown policy", func(t *testing.T) {
+		_, err := ParseNamePolicy("dos")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown name policy "dos"`)
+	})
+
+	t.Run("Successfully parses all policies", func(t *testing.T) {
+		for _, value := range []string{"posix", "windows", "portable"} {
+			policy, err := ParseNamePolicy(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, NamePolicy(value), policy)
+		}
+	})
+}
+
+func TestParseNormalizationForm(t *testing.T) {
+	t.Run("Returns error for unknown form", func(t *testing.T) {
+		_, err := ParseNormalizationForm("nfkc")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown normalization form "nfkc"`)
+	})
+
+	t.Run("Successfully parses all forms", func(t *testing.T) {
+		for _, value := range []string{"none", "nfc", "nfd"} {
+			form, err := ParseNormalizationForm(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, NormalizationForm(value), form)
+		}
+	})
+}
+
+func TestParseLegacyEncoding(t *testing.T) {
+	t.Run("Returns error for unknown encoding", func(t *testing.T) {
+		_, err := ParseLegacyEncoding("gbk")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown legacy encoding "gbk"`)
+	})
+
+	t.Run("Successfully parses all encodings", func(t *testing.T) {
+		for _, value := range []string{"none", "cp437", "shift-jis"} {
+			legacyEncoding, err := ParseLegacyEncoding(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, LegacyEncoding(value), legacyEncoding)
+		}
+	})
+}
+
+func TestFlattenName(t *testing.T) {
+	t.Run("Successfully splits forward slashes and backslashes", func(t *testing.T) {
+		r := newRepackager(Options{})
+
+		for _, entryName := range []string{"dir/sub/file.txt", `dir\sub\file.txt`, `dir/sub\file.txt`} {
+			name, err := r.flattenName(createTestZipFile(entryName, "content"), 0)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "file.txt", name)
+		}
+	})
+
+	t.Run("Successfully keeps the last path components", func(t *testing.T) {
+		file := createTestZipFile("bundle/lang/en/strings.json", "content")
+
+		for keepDepth, expectedName := range map[int]string{
+			0: "strings.json",
+			1: "strings.json",
+			2: "en/strings.json",
+			4: "bundle/lang/en/strings.json",
+			9: "bundle/lang/en/strings.json",
+		} {
+			name, err := newRepackager(Options{KeepDepth: keepDepth}).flattenName(file, 0)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, name, "keep depth %d", keepDepth)
+		}
+	})
+
+	t.Run("Successfully strips the prefix before keeping path components", func(t *testing.T) {
+		r := newRepackager(Options{KeepDepth: 3, StripPrefix: "/bundle/"})
+
+		for entryName, expectedName := range map[string]string{
+			"bundle/lang/en/strings.json": "lang/en/strings.json",
+			"bundle/x.json":               "x.json",
+			"bundles/x.json":              "bundles/x.json",
+			"other/bundle/x.json":         "other/bundle/x.json",
+		} {
+			name, err := r.flattenName(createTestZipFile(entryName, "content"), 0)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expectedName, name, "entry %s", entryName)
+		}
+	})
+
+	t.Run("Successfully sanitizes every kept path component", func(t *testing.T) {
+		r := newRepackager(Options{KeepDepth: 3, NamePolicy: NamePolicyWindows})
+
+		name, err := r.flattenName(createTestZipFile(`/a//../con\b:c.txt`, "content"), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "_/_con/b_c.txt", name)
+	})
+
+	t.Run("Successfully normalizes names to the selected form", func(t *testing.T) {
+		composedFile := createTestZipFile("linux/caf\u00e9.txt", "content")
+		decomposedFile := createTestZipFile("macos/cafe\u0301.txt", "content")
+
+		for _, form := range []NormalizationForm{NormalizationNFC, NormalizationNFD} {
+			r := newRepackager(Options{Normalization: form})
+
+			composedName, err := r.flattenName(composedFile, 0)
+			assert.NoError(t, err)
+			decomposedName, err := r.flattenName(decomposedFile, 1)
+			assert.NoError(t, err)
+
+			assert.Equal(t, composedName, decomposedName)
+		}
+	})
+
+	t.Run("Successfully keeps legacy names unchanged by default", func(t *testing.T) {
+		file := createLegacyTestZipFile(t, "dir/\x82\xa0.txt", "content")
+
+		name, err := newRepackager(Options{}).flattenName(file, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "\x82\xa0.txt", name)
+	})
+
+	t.Run("Successfully decodes legacy names", func(t *testing.T) {
+		file := createLegacyTestZipFile(t, "dir/\x82\xa0.txt", "content")
+
+		cp437Name, err := newRepackager(Options{LegacyEncoding: LegacyEncodingCP437}).flattenName(file, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "é\u00e1.txt", cp437Name)
+
+		shiftJISName, err := newRepackager(Options{LegacyEncoding: LegacyEncodingShiftJIS}).flattenName(file, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "あ.txt", shiftJISName)
+	})
+
+	t.Run("Successfully decodes Shift-JIS names before splitting backslashes", func(t *testing.T) {
+		// The Shift-JIS encoding of "ソ" ends with the byte of a backslash.
+		file := createLegacyTestZipFile(t, "dir\\\x83\x5c.txt", "content")
+
+		name, err := newRepackager(Options{LegacyEncoding: LegacyEncodingShiftJIS}).flattenName(file, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ソ.txt", name)
+	})
+
+	t.Run("Successfully ignores the legacy encoding for UTF-8 names", func(t *testing.T) {
+		file := createTestZipFile("dir/café.txt", "content")
+
+		name, err := newRepackager(Options{LegacyEncoding: LegacyEncodingCP437}).flattenName(file, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "café.txt", name)
+	})
+}
+
+func TestSanitizeName(t *testing.T) {
+	t.Run("Successfully keeps names legal on POSIX", func(t *testing.T) {
+		assert.Equal(t, "a:b*c?.txt", sanitizeName("a:b*c?.txt", NamePolicyPOSIX))
+		assert.Equal(t, "CON.txt", sanitizeName("CON.txt", NamePolicyPOSIX))
+		assert.Equal(t, "trailing.", sanitizeName("trailing.", NamePolicyPOSIX))
+		assert.Equal(t, "a_b", sanitizeName("a\x00b", NamePolicyPOSIX))
+	})
+
+	t.Run("Successfully replaces characters illegal on Windows", func(t *testing.T) {
+		assert.Equal(t, "a_b_c_d_.txt", sanitizeName(`a:b*c?d|.txt`, NamePolicyWindows))
+		assert.Equal(t, "tab_name", sanitizeName("tab\tname", NamePolicyWindows))
+		assert.Equal(t, "café.txt", sanitizeName("café.txt", NamePolicyWindows))
+	})
+
+	t.Run("Successfully strips trailing dots and spaces on Windows", func(t *testing.T) {
+		assert.Equal(t, "name", sanitizeName("name. .", NamePolicyWindows))
+		assert.Equal(t, "_", sanitizeName("...", NamePolicyWindows))
+	})
+
+	t.Run("Successfully renames reserved device names on Windows", func(t *testing.T) {
+		assert.Equal(t, "_CON", sanitizeName("CON", NamePolicyWindows))
+		assert.Equal(t, "_nul.txt", sanitizeName("nul.txt", NamePolicyWindows))
+		assert.Equal(t, "_LPT1.tar.gz", sanitizeName("LPT1.tar.gz", NamePolicyWindows))
+		assert.Equal(t, "CONSOLE.txt", sanitizeName("CONSOLE.txt", NamePolicyWindows))
+	})
+
+	t.Run("Successfully restricts names to the portable character set", func(t *testing.T) {
+		assert.Equal(t, "caf___1_.txt", sanitizeName("café (1).txt", NamePolicyPortable))
+		assert.Equal(t, "_AUX", sanitizeName("AUX", NamePolicyPortable))
+		assert.Equal(t, "_", sanitizeName("\xff", NamePolicyPortable))
+	})
+
+	t.Run("Successfully renames special directory names", func(t *testing.T) {
+		assert.Equal(t, "_", sanitizeName(".", NamePolicyPOSIX))
+		assert.Equal(t, "__", sanitizeName("..", NamePolicyPOSIX))
+		assert.Equal(t, "_", sanitizeName("", NamePolicyPOSIX))
+	})
+}
+
+func TestFoldCase(t *testing.T) {
+	t.Run("Successfully maps names differing by case to the same key", func(t *testing.T) {
+		assert.Equal(t, foldCase("README.md"), foldCase("readme.MD"))
+		assert.Equal(t, foldCase("\u212Aelvin"), foldCase("kelvin"))
+		assert.NotEqual(t, foldCase("readme.md"), foldCase("readme.txt"))
+	})
+}
+
+func TestAnnotateCaseCollisions(t *testing.T) {
+	t.Run("Successfully annotates and groups colliding names", func(t *testing.T) {
+		files := map[string]FileInfo{
+			"a.txt": {}, "A.txt": {}, "A.TXT": {},
+			"b.txt": {},
+		}
+
+		collisions := annotateCaseCollisions(files)
+
+		assert.Equal(t, [][]string{{"A.TXT", "A.txt", "a.txt"}}, collisions)
+		assert.Equal(t, []string{"A.txt", "a.txt"}, files["A.TXT"].CaseCollisions)
+		assert.Equal(t, []string{"A.TXT", "A.txt"}, files["a.txt"].CaseCollisions)
+		assert.Empty(t, files["b.txt"].CaseCollisions)
+	})
+
+	t.Run("Successfully returns no groups without collisions", func(t *testing.T) {
+		collisions := annotateCaseCollisions(map[string]FileInfo{"a.txt": {}, "b.txt": {}})
+
+		assert.Empty(t, collisions)
+	})
+}
+
+// createLegacyTestZipFile builds a one-entry ZIP in memory whose name is stored as raw bytes
+// without the UTF-8 flag.
+func createLegacyTestZipFile(t *testing.T, name, content string) *zip.File {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:    name,
+		Method:  zip.Deflate,
+		NonUTF8: true,
+	})
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	return reader.File[0]
+}
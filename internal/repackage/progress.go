@@ -0,0 +1,85 @@
+package repackage
+
+import "io"
+
+// progressReportBytes is the number of bytes transferred between two progress reports within
+// an entry, so that large entries still report progress.
+const progressReportBytes = 4 << 20
+
+// ProgressPhase is the phase of a run that a progress report refers to.
+type ProgressPhase string
+
+const (
+	// ProgressScanning counts the input entries examined while flattening and deduplicating.
+	ProgressScanning ProgressPhase = "scanning"
+
+	// ProgressWriting counts the files written to the output ZIP.
+	ProgressWriting ProgressPhase = "writing"
+)
+
+// Progress describes how far a run has gone.
+type Progress struct {
+	Phase ProgressPhase
+
+	// Entries is the number of entries of the phase processed so far, out of TotalEntries.
+	Entries      int
+	TotalEntries int
+
+	// BytesRead is the number of decompressed bytes read from the input ZIP since the start of
+	// the run, including entries read to compare or hash them.
+	BytesRead int64
+
+	// BytesWritten is the number of bytes of file content written to the output ZIP.
+	BytesWritten int64
+}
+
+// startPhase resets the entry counts for a new phase and reports it.
+func (r *repackager) startPhase(phase ProgressPhase, totalEntries int) {
+	r.progress.Phase = phase
+	r.progress.Entries = 0
+	r.progress.TotalEntries = totalEntries
+	r.reportProgress()
+}
+
+// completeEntries sets the number of entries of the current phase processed so far and reports it.
+func (r *repackager) completeEntries(entries int) {
+	r.progress.Entries = entries
+	r.reportProgress()
+}
+
+// countBytes adds transferred bytes to the progress, reporting it every progressReportBytes.
+func (r *repackager) countBytes(read, written int64) {
+	r.progress.BytesRead += read
+	r.progress.BytesWritten += written
+
+	r.unreportedBytes += read + written
+	if r.unreportedBytes >= progressReportBytes {
+		r.reportProgress()
+	}
+}
+
+// reportProgress passes the current progress to the callback of the options, if any.
+func (r *repackager) reportProgress() {
+	r.unreportedBytes = 0
+	if r.options.Progress != nil {
+		r.options.Progress(r.progress)
+	}
+}
+
+// countingReader passes the number of bytes read from an entry to a function as they are read.
+type countingReader struct {
+	reader io.ReadCloser
+	count  func(n int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if n > 0 {
+		c.count(int64(n))
+	}
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.reader.Close()
+}
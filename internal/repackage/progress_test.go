@@ -0,0 +1,73 @@
+package repackage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("Successfully reports entries and bytes of each phase", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "progress_input.zip")
+		err := makeTestZip(inputPath, map[string]string{
+			"a/foo.txt":  "small",
+			"b/foo.txt":  "larger content",
+			"docs/a.txt": "a",
+			".DS_Store":  "metadata",
+		})
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		var reports []Progress
+		_, err = Run(context.Background(), []string{inputPath}, filepath.Join(tempDir, "progress_output.zip"), Options{
+			Progress: func(progress Progress) { reports = append(reports, progress) },
+		})
+		require.NoError(t, err)
+
+		require.NotEmpty(t, reports)
+		assert.Equal(t, Progress{Phase: ProgressScanning, TotalEntries: 4}, reports[0])
+
+		var lastScanning Progress
+		for _, report := range reports {
+			if report.Phase == ProgressScanning {
+				lastScanning = report
+			}
+		}
+		assert.Equal(t, 4, lastScanning.Entries)
+		assert.Zero(t, lastScanning.BytesWritten)
+
+		// Entries of different sizes are never hashed, so only the written files are read.
+		assert.Equal(t, Progress{
+			Phase:        ProgressWriting,
+			Entries:      2,
+			TotalEntries: 2,
+			BytesRead:    int64(len("larger content") + len("a")),
+			BytesWritten: int64(len("larger content") + len("a")),
+		}, reports[len(reports)-1])
+	})
+
+	t.Run("Successfully reports progress within large entries", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "large_input.zip")
+		content := strings.Repeat("x", 3*progressReportBytes)
+		err := makeTestZip(inputPath, map[string]string{"large.bin": content})
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		var writingReports int
+		_, err = Run(context.Background(), []string{inputPath}, filepath.Join(tempDir, "large_output.zip"), Options{
+			Progress: func(progress Progress) {
+				if progress.Phase == ProgressWriting {
+					writingReports++
+				}
+			},
+		})
+		require.NoError(t, err)
+
+		// Start of the phase, a report per progressReportBytes transferred, and the written entry.
+		assert.GreaterOrEqual(t, writingReports, 4)
+	})
+}
@@ -0,0 +1,195 @@
+package repackage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rewriteSeparator separates the pattern from the replacement in a rewrite rule.
+const rewriteSeparator = "=>"
+
+// RewriteRule rewrites the paths of entries matching a regular expression before they are flattened.
+type RewriteRule struct {
+	Pattern *regexp.Regexp
+
+	// Replacement may reference the groups of the pattern as $1 or ${name}.
+	Replacement string
+}
+
+// RenameTemplate computes flattened names from placeholders describing each entry:
+//
+//	{base}    base name of the entry, e.g. "logo.png"
+//	{stem}    base name without its extension, e.g. "logo"
+//	{ext}     extension including the dot, e.g. ".png", or an empty string
+//	{parent}  name of the directory containing the entry, or an empty string
+//	{dir}     full directory path of the entry, or an empty string
+//	{path}    the name the entry would have without a template, honoring the kept depth
+//	{index}   zero-based position of the entry in the input archive
+//	{shaN}    first N hexadecimal digits of the SHA-256 of the content, e.g. {sha8}
+//	{sha}     full hexadecimal SHA-256 of the content
+//
+// Slashes in the expanded name create nested paths. Placeholders are expanded after rewrite
+// rules and prefix stripping are applied, and the result is then sanitized.
+type RenameTemplate struct {
+	source string
+	parts  []templatePart
+}
+
+// templatePart is either a literal text or a placeholder of a RenameTemplate.
+type templatePart struct {
+	literal     string
+	placeholder string
+
+	// hashDigits is the number of hexadecimal digits of a {sha} or {shaN} placeholder.
+	hashDigits int
+}
+
+// renameContext holds the values available to the placeholders of a RenameTemplate.
+type renameContext struct {
+	// components are the components of the entry path after rewriting and prefix stripping.
+	components []string
+
+	// keptComponents are the trailing components kept according to Options.KeepDepth.
+	keptComponents []string
+
+	index int
+
+	// hash computes the SHA-256 of the entry, and is only called by hash placeholders.
+	hash func() (Digest, error)
+}
+
+// ParseRewriteRule parses a rule of the form "PATTERN=>REPLACEMENT".
+func ParseRewriteRule(value string) (RewriteRule, error) {
+	pattern, replacement, found := strings.Cut(value, rewriteSeparator)
+	if !found {
+		return RewriteRule{}, fmt.Errorf("rewrite rule %q must have the form PATTERN%sREPLACEMENT", value, rewriteSeparator)
+	}
+
+	compiledPattern, err := regexp.Compile(pattern)
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("invalid pattern in rewrite rule %q: %w", value, err)
+	}
+
+	return RewriteRule{Pattern: compiledPattern, Replacement: replacement}, nil
+}
+
+// String returns the rule in the form accepted by ParseRewriteRule.
+func (r RewriteRule) String() string {
+	return r.Pattern.String() + rewriteSeparator + r.Replacement
+}
+
+// ParseRenameTemplate parses a template such as "{parent}_{base}", rejecting unknown
+// placeholders and unbalanced braces.
+func ParseRenameTemplate(value string) (*RenameTemplate, error) {
+	template := &RenameTemplate{source: value}
+
+	for remaining := value; remaining != ""; {
+		openIndex := strings.IndexAny(remaining, "{}")
+		if openIndex < 0 {
+			template.parts = append(template.parts, templatePart{literal: remaining})
+			break
+		}
+		if remaining[openIndex] == '}' {
+			return nil, fmt.Errorf("invalid rename template %q: unexpected '}'", value)
+		}
+		if openIndex > 0 {
+			template.parts = append(template.parts, templatePart{literal: remaining[:openIndex]})
+		}
+
+		closeIndex := strings.IndexByte(remaining[openIndex:], '}')
+		if closeIndex < 0 {
+			return nil, fmt.Errorf("invalid rename template %q: unclosed '{'", value)
+		}
+
+		part, err := parsePlaceholder(remaining[openIndex+1 : openIndex+closeIndex])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rename template %q: %w", value, err)
+		}
+		template.parts = append(template.parts, part)
+		remaining = remaining[openIndex+closeIndex+1:]
+	}
+
+	if template.String() == "" {
+		return nil, fmt.Errorf("invalid rename template: it must not be empty")
+	}
+
+	return template, nil
+}
+
+// parsePlaceholder validates the name of a placeholder found between braces.
+func parsePlaceholder(name string) (templatePart, error) {
+	switch name {
+	case "base", "stem", "ext", "parent", "dir", "path", "index":
+		return templatePart{placeholder: name}, nil
+	case "sha":
+		return templatePart{placeholder: "sha", hashDigits: hex.EncodedLen(32)}, nil
+	}
+
+	if digits, isHashPrefix := strings.CutPrefix(name, "sha"); isHashPrefix {
+		hashDigits, err := strconv.Atoi(digits)
+		if err != nil || hashDigits < 1 || hashDigits > hex.EncodedLen(32) {
+			return templatePart{}, fmt.Errorf("placeholder {%s} must use between 1 and %d digits", name, hex.EncodedLen(32))
+		}
+		return templatePart{placeholder: "sha", hashDigits: hashDigits}, nil
+	}
+
+	return templatePart{}, fmt.Errorf("unknown placeholder {%s}", name)
+}
+
+// String returns the template as it was parsed.
+func (t *RenameTemplate) String() string {
+	return t.source
+}
+
+// expand computes the name of an entry from the template.
+func (t *RenameTemplate) expand(context renameContext) (string, error) {
+	baseName := ""
+	if len(context.components) > 0 {
+		baseName = context.components[len(context.components)-1]
+	}
+	directories := context.components[:max(len(context.components)-1, 0)]
+
+	var builder strings.Builder
+	for _, part := range t.parts {
+		switch part.placeholder {
+		case "":
+			builder.WriteString(part.literal)
+		case "base":
+			builder.WriteString(baseName)
+		case "stem":
+			builder.WriteString(strings.TrimSuffix(baseName, path.Ext(baseName)))
+		case "ext":
+			builder.WriteString(path.Ext(baseName))
+		case "parent":
+			if len(directories) > 0 {
+				builder.WriteString(directories[len(directories)-1])
+			}
+		case "dir":
+			builder.WriteString(strings.Join(directories, "/"))
+		case "path":
+			builder.WriteString(strings.Join(context.keptComponents, "/"))
+		case "index":
+			builder.WriteString(strconv.Itoa(context.index))
+		case "sha":
+			contentHash, err := context.hash()
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(contentHash.String()[:part.hashDigits])
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// rewritePath applies the rewrite rules in order to a slash-separated path.
+func rewritePath(entryPath string, rules []RewriteRule) string {
+	for _, rule := range rules {
+		entryPath = rule.Pattern.ReplaceAllString(entryPath, rule.Replacement)
+	}
+	return entryPath
+}
@@ -0,0 +1,197 @@
+package repackage
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRewriteRule(t *testing.T) {
+	t.Run("Returns error without separator", func(t *testing.T) {
+		_, err := ParseRewriteRule("^docs/")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must have the form PATTERN=>REPLACEMENT")
+	})
+
+	t.Run("Returns error with invalid pattern", func(t *testing.T) {
+		_, err := ParseRewriteRule("([a-z]=>x")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid pattern")
+	})
+
+	t.Run("Successfully parses rule with empty replacement", func(t *testing.T) {
+		rule, err := ParseRewriteRule("^build/[^/]+/=>")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "^build/[^/]+/", rule.Pattern.String())
+		assert.Empty(t, rule.Replacement)
+		assert.Equal(t, "^build/[^/]+/=>", rule.String())
+	})
+}
+
+func TestParseRenameTemplate(t *testing.T) {
+	t.Run("Returns error with invalid templates", func(t *testing.T) {
+		for template, message := range map[string]string{
+			"":              "must not be empty",
+			"{base":         "unclosed '{'",
+			"base}":         "unexpected '}'",
+			"{name}":        "unknown placeholder {name}",
+			"{sha0}-{base}": "between 1 and 64 digits",
+			"{sha65}":       "between 1 and 64 digits",
+			"{shax}":        "between 1 and 64 digits",
+		} {
+			_, err := ParseRenameTemplate(template)
+
+			assert.Error(t, err, template)
+			assert.Contains(t, err.Error(), message, template)
+		}
+	})
+
+	t.Run("Successfully parses templates", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{parent}_{base}")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "{parent}_{base}", template.String())
+	})
+}
+
+func TestRenameTemplateExpand(t *testing.T) {
+	contentHash := sha256Digest("content")
+	context := renameContext{
+		components:     []string{"assets", "img", "logo.png"},
+		keptComponents: []string{"img", "logo.png"},
+		index:          7,
+		hash:           func() (Digest, error) { return contentHash, nil },
+	}
+
+	t.Run("Successfully expands all placeholders", func(t *testing.T) {
+		for source, expected := range map[string]string{
+			"{base}":               "logo.png",
+			"{stem}-v2{ext}":       "logo-v2.png",
+			"{parent}_{base}":      "img_logo.png",
+			"{dir}/{base}":         "assets/img/logo.png",
+			"{path}":               "img/logo.png",
+			"{index}-{base}":       "7-logo.png",
+			"{sha8}-{base}":        contentHash.String()[:8] + "-logo.png",
+			"{sha}":                contentHash.String(),
+			"literal without vars": "literal without vars",
+		} {
+			template, err := ParseRenameTemplate(source)
+			require.NoError(t, err)
+
+			name, err := template.expand(context)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, name, source)
+		}
+	})
+
+	t.Run("Successfully expands directories of root entries as empty", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{parent}_{dir}_{base}")
+		require.NoError(t, err)
+
+		name, err := template.expand(renameContext{components: []string{"logo.png"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "__logo.png", name)
+	})
+
+	t.Run("Returns error when hashing fails", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{sha8}")
+		require.NoError(t, err)
+
+		_, err = template.expand(renameContext{hash: func() (Digest, error) {
+			return "", errors.New("read failure")
+		}})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "read failure")
+	})
+
+	t.Run("Successfully skips hashing without hash placeholders", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{base}")
+		require.NoError(t, err)
+
+		name, err := template.expand(renameContext{
+			components: []string{"logo.png"},
+			hash:       func() (Digest, error) { panic("hash should not be computed") },
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "logo.png", name)
+	})
+}
+
+func TestRenameEntries(t *testing.T) {
+	t.Run("Successfully rewrites paths before flattening", func(t *testing.T) {
+		rule, err := ParseRewriteRule(`^v\d+/(.*)\.jpeg$=>$1.jpg`)
+		require.NoError(t, err)
+		r := newRepackager(Options{RewriteRules: []RewriteRule{rule}, KeepDepth: 2})
+
+		name, err := r.flattenName(createTestZipFile("v2/img/photo.jpeg", "content"), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "img/photo.jpg", name)
+	})
+
+	t.Run("Successfully sanitizes names produced by templates", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{parent}:{base}")
+		require.NoError(t, err)
+		r := newRepackager(Options{RenameTemplate: template, NamePolicy: NamePolicyWindows})
+
+		name, err := r.flattenName(createTestZipFile("img/logo.png", "content"), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "img_logo.png", name)
+	})
+
+	t.Run("Successfully keeps files whose templated names are unique", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{parent}_{base}")
+		require.NoError(t, err)
+		englishFile := createTestZipFile("lang/en/strings.json", "english")
+		frenchFile := createTestZipFile("lang/fr/strings.json", "french")
+
+		result, err := newRepackager(Options{RenameTemplate: template}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{englishFile, frenchFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]*zip.File{"en_strings.json": englishFile, "fr_strings.json": frenchFile}, result)
+	})
+
+	t.Run("Successfully deduplicates files whose templated names collide", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{sha8}{ext}")
+		require.NoError(t, err)
+		file := createTestZipFile("a/logo.png", "png bytes")
+		copiedFile := createTestZipFile("b/logo-copy.png", "png bytes")
+		contentHash := sha256Digest("png bytes")
+
+		hashCache := NewHashCache()
+		result, err := newRepackager(Options{RenameTemplate: template, HashCache: hashCache}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{file, copiedFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]*zip.File{contentHash.String()[:8] + ".png": file}, result)
+		assert.Equal(t, HashCacheStats{Hits: 2, Misses: 2}, hashCache.Stats(), "Template hashes should be reused")
+	})
+
+	t.Run("Returns error when templated names collide with different content", func(t *testing.T) {
+		template, err := ParseRenameTemplate("{ext}")
+		require.NoError(t, err)
+
+		_, err = newRepackager(Options{RenameTemplate: template}).flattenAndDeduplicate(context.Background(), []*zip.File{
+			createTestZipFile("a/one.txt", "content1"),
+			createTestZipFile("b/two.txt", "content2"),
+		})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "identical sizes but differing content")
+	})
+}
@@ -2,108 +2,672 @@ package repackage
 
 import (
 	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
-	"path/filepath"
+	"slices"
+	"strings"
 )
 
+// MergedPathSeparator joins the path of an input archive and the path of one of its entries in
+// the entry paths of merged runs, such as "vendor.zip!/lib/logo.png".
+const MergedPathSeparator = "!/"
+
 // FileInfo stores metadata about a file in the output ZIP archive.
 type FileInfo struct {
+	// Full path of the file in the source ZIP before flattening. When several archives are
+	// merged, it is prefixed with the path of the source archive, see EntryPath.
+	OriginalPath string
+
+	// HashAlgorithm is the algorithm Hash was computed with.
+	HashAlgorithm HashAlgorithm
+
+	// Checksum of the file contents.
+	Hash Digest
+
+	// Size of the file contents in bytes.
+	Size int64
+
+	// Full paths of the files with the same flattened name that were dropped in favor of this one.
+	Duplicates []string
+
+	// Other output names that only differ from this one by letter case.
+	// It is only populated when Options.WarnCaseCollisions is set.
+	CaseCollisions []string
+
+	// Files with identical content that were dropped in favor of this one.
+	// It is only populated when Options.DedupeContent is set.
+	Aliases []Alias
+}
+
+// Alias describes a file that was dropped because another file has identical content.
+type Alias struct {
+	// Name the file would have had in the output ZIP.
+	Name string
+
 	// Full path of the file in the source ZIP before flattening.
 	OriginalPath string
+}
 
-	// SHA-256 checksum of the file contents.
-	Hash [32]byte
+// SkipReason explains why an input entry has no file of its own in the output ZIP.
+type SkipReason string
+
+const (
+	// SkipReasonSymlink marks symbolic links, whose targets are not followed.
+	SkipReasonSymlink SkipReason = "symlink"
+
+	// SkipReasonMetadata marks system and application metadata files such as ".DS_Store".
+	SkipReasonMetadata SkipReason = "metadata"
+
+	// SkipReasonDuplicate marks entries dropped in favor of another entry with the same flattened name.
+	SkipReasonDuplicate SkipReason = "duplicate"
+
+	// SkipReasonDuplicateContent marks entries dropped in favor of another entry with identical content.
+	SkipReasonDuplicateContent SkipReason = "duplicate_content"
+)
+
+// SkippedEntry describes an input entry that was not written to the output ZIP.
+type SkippedEntry struct {
+	// Full path of the entry in the source ZIP.
+	Path string
+
+	Reason SkipReason
+
+	// KeptAs is the name of the output file kept instead of the entry. It is only set for
+	// duplicates.
+	KeptAs string
 }
 
-func Run(inputPath, outputPath string) (map[string]FileInfo, error) {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open input zip: %w", err)
+// CanonicalRule selects which of the files with identical content is kept by content deduplication.
+type CanonicalRule string
+
+const (
+	// CanonicalFirst keeps the file that appears first in the input archive.
+	CanonicalFirst CanonicalRule = "first"
+
+	// CanonicalShortest keeps the file with the shortest name, then the lexicographically smallest one.
+	CanonicalShortest CanonicalRule = "shortest"
+
+	// CanonicalLexical keeps the file with the lexicographically smallest name.
+	CanonicalLexical CanonicalRule = "lexical"
+)
+
+// Options controls how the input archives are flattened and deduplicated.
+type Options struct {
+	// VerifySizes measures the actual decompressed size of every entry instead of trusting the
+	// size declared in its header, and uses the measured sizes to pick the larger duplicate.
+	VerifySizes bool
+
+	// NamePolicy selects how flattened names are sanitized. Entries whose sanitized names are
+	// equal are deduplicated like entries sharing a name. Defaults to NamePolicyPOSIX.
+	NamePolicy NamePolicy
+
+	// Normalization selects the Unicode normalization form applied to flattened names before
+	// deduplication. Defaults to NormalizationNone.
+	Normalization NormalizationForm
+
+	// LegacyEncoding selects how names stored without the ZIP UTF-8 flag are decoded.
+	// Defaults to LegacyEncodingNone.
+	LegacyEncoding LegacyEncoding
+
+	// KeepDepth is the number of trailing path components kept in flattened names, so that
+	// "lang/en/strings.json" becomes "en/strings.json" with a depth of two. Deduplication then
+	// compares these relative paths. Zero and one only keep the base name.
+	KeepDepth int
+
+	// StripPrefix is a leading directory removed from entry paths before KeepDepth is applied,
+	// so that it never appears in flattened names. Paths outside of it are left unchanged.
+	StripPrefix string
+
+	// RewriteRules are applied in order to the full path of every entry before StripPrefix and
+	// KeepDepth, so that renamed entries are deduplicated under their new names.
+	RewriteRules []RewriteRule
+
+	// RenameTemplate, when set, computes flattened names instead of KeepDepth. Entries whose
+	// names collide are deduplicated, and conflicting content fails the run.
+	RenameTemplate *RenameTemplate
+
+	// EmbedManifest adds a manifest entry to the output ZIP that lists, for every file, its
+	// original path, checksum, size and the duplicates dropped in its favor.
+	EmbedManifest bool
+
+	// ManifestFormat selects the format of the embedded manifest. Defaults to ManifestJSON.
+	ManifestFormat ManifestFormat
+
+	// CaseInsensitive deduplicates names that only differ by letter case, as they would
+	// overwrite each other when extracted on a case-insensitive file system.
+	CaseInsensitive bool
+
+	// WarnCaseCollisions keeps names that only differ by letter case but reports them.
+	// It has no effect when CaseInsensitive is set.
+	WarnCaseCollisions bool
+
+	// DedupeContent keeps a single copy of files that have identical content under different
+	// names, and records the other names as aliases of the kept file.
+	DedupeContent bool
+
+	// CanonicalRule selects which file is kept by content deduplication. Defaults to CanonicalFirst.
+	CanonicalRule CanonicalRule
+
+	// HashAlgorithm selects the checksum used to compare entries and recorded for every file.
+	// Defaults to HashSHA256.
+	HashAlgorithm HashAlgorithm
+
+	// HashCache caches the checksums of the input entries. It can be shared with validation to
+	// inspect its statistics after the run. A new cache is used when it is nil.
+	HashCache *HashCache
+
+	// Logger receives an event for every skipped, dropped and written entry. Nothing is logged
+	// when it is nil.
+	Logger *slog.Logger
+
+	// Progress, when set, is called with the progress of the run at the start of each phase,
+	// after each entry, and every few megabytes read or written within an entry.
+	Progress func(Progress)
+
+	// Previous, when set, is the output of an earlier run whose entries are copied instead of
+	// processing their source entries again when these are unchanged. The output is the same as
+	// without it, and may replace the previous output.
+	Previous *PreviousOutput
+}
+
+// Result holds the outcome of a repackaging run.
+type Result struct {
+	// Files maps each file name in the output ZIP to its metadata.
+	Files map[string]FileInfo
+
+	// SizeMismatches lists the input entries whose declared size differs from their actual size.
+	// It is only populated when Options.VerifySizes is set.
+	SizeMismatches []SizeMismatch
+
+	// Skipped lists the input entries that were not written to the output ZIP, sorted by path.
+	// Directories are not listed, as flattening removes them by design.
+	Skipped []SkippedEntry
+
+	// CaseCollisions lists the groups of output names that only differ by letter case.
+	// It is only populated when Options.WarnCaseCollisions is set.
+	CaseCollisions [][]string
+
+	// ManifestName is the name of the manifest entry in the output ZIP, which is not listed in
+	// Files. It is only set when Options.EmbedManifest is set.
+	ManifestName string
+}
+
+// SizeMismatch describes an input entry whose header declares a wrong uncompressed size.
+type SizeMismatch struct {
+	Path         string
+	DeclaredSize int64
+	ActualSize   int64
+}
+
+// repackager carries the options and the state shared by the phases of a single run.
+type repackager struct {
+	options Options
+
+	// hashes caches the size and hash of each entry read during the run.
+	hashes *HashCache
+
+	// algorithm is the hash algorithm of the options, or its default.
+	algorithm HashAlgorithm
+
+	logger *slog.Logger
+
+	progress Progress
+
+	// unreportedBytes counts the bytes transferred since the last progress report.
+	unreportedBytes int64
+
+	sizeMismatches []SizeMismatch
+
+	// skipped lists the symlinks and metadata files found while flattening.
+	skipped []SkippedEntry
+
+	// aliases maps the name of each file kept by content deduplication to the files it replaces.
+	aliases map[string][]Alias
+
+	// duplicates maps the name of each file kept by name deduplication to the paths of the
+	// files it replaces.
+	duplicates map[string][]string
+
+	// archives maps every entry to the path of its input archive when several archives are
+	// merged. It is nil for a single archive.
+	archives map[*zip.File]string
+
+	// reused maps the unchanged input entries to their copy in the previous output.
+	reused map[*zip.File]*zip.File
+}
+
+func newRepackager(options Options) *repackager {
+	hashes := options.HashCache
+	if hashes == nil {
+		hashes = NewHashCache()
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
-	defer reader.Close()
 
-	deduplicatedFiles, err := flattenAndDeduplicate(reader.File)
+	return &repackager{
+		options:    options,
+		hashes:     hashes,
+		algorithm:  options.HashAlgorithm.OrDefault(),
+		logger:     logger,
+		aliases:    make(map[string][]Alias),
+		duplicates: make(map[string][]string),
+		reused:     make(map[*zip.File]*zip.File),
+	}
+}
+
+// ParseCanonicalRule converts a command-line value into a CanonicalRule.
+func ParseCanonicalRule(value string) (CanonicalRule, error) {
+	switch rule := CanonicalRule(value); rule {
+	case CanonicalFirst, CanonicalShortest, CanonicalLexical:
+		return rule, nil
+	default:
+		return "", fmt.Errorf("unknown canonical rule %q: expected %s, %s or %s",
+			value, CanonicalFirst, CanonicalShortest, CanonicalLexical)
+	}
+}
+
+// Run flattens and deduplicates the input archives into the output archive. The entries of all
+// the archives share a single namespace, so that files with the same flattened name are
+// deduplicated across archives, in the order of the archives. Failures are reported as an
+// *InputError, a *ConflictError or an *OutputError, and cancelling the context stops the run
+// between entries with the context error.
+func Run(ctx context.Context, inputPaths []string, outputPath string, options Options) (*Result, error) {
+	if len(inputPaths) == 0 {
+		return nil, &InputError{Err: errors.New("no input zip to repackage")}
+	}
+
+	r := newRepackager(options)
+	if len(inputPaths) > 1 {
+		r.archives = make(map[*zip.File]string)
+	}
+
+	var files []*zip.File
+	for _, inputPath := range inputPaths {
+		reader, err := zip.OpenReader(inputPath)
+		if err != nil {
+			return nil, &InputError{Err: fmt.Errorf("failed to open input zip %s: %w", inputPath, err)}
+		}
+		defer reader.Close()
+
+		if r.archives != nil {
+			for _, file := range reader.File {
+				r.archives[file] = inputPath
+			}
+		}
+		files = append(files, reader.File...)
+	}
+
+	if options.Previous != nil {
+		previousReader, err := zip.OpenReader(options.Previous.Path)
+		if err != nil {
+			r.logger.Warn("cannot reuse the previous output", "path", options.Previous.Path, "error", err)
+		} else {
+			defer previousReader.Close()
+			r.matchPreviousOutput(previousReader.File, files)
+			r.logger.Info("matched previous output", "path", options.Previous.Path, "unchanged", len(r.reused))
+		}
+	}
+
+	deduplicatedFiles, err := r.flattenAndDeduplicate(ctx, files)
 	if err != nil {
 		return nil, err
 	}
 
-	outputFileRegistry, err := createOutputZip(deduplicatedFiles, outputPath)
+	if options.DedupeContent {
+		if err := r.deduplicateContent(ctx, deduplicatedFiles, files); err != nil {
+			return nil, err
+		}
+	}
+
+	outputFileRegistry, err := r.createOutputZip(ctx, deduplicatedFiles, outputPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return outputFileRegistry, nil
+	result := &Result{
+		Files:          outputFileRegistry,
+		SizeMismatches: r.sizeMismatches,
+		Skipped:        r.skippedEntries(outputFileRegistry),
+	}
+
+	if options.EmbedManifest {
+		result.ManifestName = r.manifestName()
+	}
+
+	if options.WarnCaseCollisions && !options.CaseInsensitive {
+		result.CaseCollisions = annotateCaseCollisions(outputFileRegistry)
+	}
+
+	return result, nil
+}
+
+// EntryPath returns the path identifying an entry in the result of a run: its name when a single
+// archive is repackaged, or the path of its archive and its name joined by MergedPathSeparator
+// when several archives are merged.
+func EntryPath(archivePath, name string, isMerged bool) string {
+	if !isMerged {
+		return name
+	}
+	return archivePath + MergedPathSeparator + name
+}
+
+// entryPath returns the path identifying an entry of the input archives in the result.
+func (r *repackager) entryPath(file *zip.File) string {
+	return EntryPath(r.archives[file], file.Name, r.archives != nil)
 }
 
 // flattenAndDeduplicate processes ZIP entries by:
-// - Removing directory paths (flattening)
-// - Keeping larger files when duplicates exist
+// - Removing directory paths (flattening), then decoding, normalizing and sanitizing the kept names
+// - Keeping larger files when duplicates exist, optionally ignoring letter case
 // - Verifying identical content for same-size files
-// Returns a map of base filenames to their corresponding ZIP entries.
-func flattenAndDeduplicate(files []*zip.File) (map[string]*zip.File, error) {
-	// Map to track the largest file by base name.
+// Returns a map of flattened names to their corresponding ZIP entries.
+func (r *repackager) flattenAndDeduplicate(ctx context.Context, files []*zip.File) (map[string]*zip.File, error) {
+	// Map to track the largest file by flattened name.
 	deduplicatedFiles := make(map[string]*zip.File, len(files))
 
-	for _, currentFile := range files {
-		if currentFile.FileInfo().IsDir() || isSymlink(currentFile) || isMetadataFile(currentFile.Name) {
+	// Map from the key that identifies duplicates to the flattened name currently kept for it.
+	// Keys only differ from flattened names when letter case is ignored.
+	keptNames := make(map[string]string, len(files))
+
+	// Map from the key that identifies duplicates to the paths of the dropped files.
+	droppedPaths := make(map[string][]string)
+
+	r.startPhase(ProgressScanning, len(files))
+	for index, currentFile := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if index > 0 {
+			r.completeEntries(index)
+		}
+
+		if isSymlink(currentFile) {
+			r.skipped = append(r.skipped, SkippedEntry{Path: r.entryPath(currentFile), Reason: SkipReasonSymlink})
+			r.logger.Info("skipped symlink", "path", r.entryPath(currentFile))
+			continue
+		}
+		if currentFile.FileInfo().IsDir() {
+			r.logger.Debug("skipped directory", "path", r.entryPath(currentFile))
 			continue
 		}
+		if isMetadataFile(currentFile.Name) {
+			r.skipped = append(r.skipped, SkippedEntry{Path: r.entryPath(currentFile), Reason: SkipReasonMetadata})
+			r.logger.Info("skipped metadata file", "path", r.entryPath(currentFile))
+			continue
+		}
+
+		// Sizes are resolved before the duplicate check so that, in VerifySizes mode,
+		// every entry is measured and its declared size mismatch is recorded.
+		currentSize, err := r.sizeOf(currentFile)
+		if err != nil {
+			return nil, &InputError{Err: fmt.Errorf("failed measuring file \"%s\": %w", r.entryPath(currentFile), err)}
+		}
+
+		flattenedName, err := r.flattenName(currentFile, index)
+		if err != nil {
+			return nil, fmt.Errorf("failed flattening name of file \"%s\": %w", r.entryPath(currentFile), err)
+		}
 
-		baseName := filepath.Base(currentFile.Name)
-		if existingFile, isDuplicateName := deduplicatedFiles[baseName]; isDuplicateName {
-			existingSize := existingFile.FileInfo().Size()
-			currentSize := currentFile.FileInfo().Size()
+		duplicateKey := r.duplicateKey(flattenedName)
+		if existingName, isDuplicateName := keptNames[duplicateKey]; isDuplicateName {
+			existingFile := deduplicatedFiles[existingName]
+			existingSize, err := r.sizeOf(existingFile)
+			if err != nil {
+				return nil, &InputError{Err: fmt.Errorf("failed measuring file \"%s\": %w", r.entryPath(existingFile), err)}
+			}
 
 			switch {
 			case existingSize == currentSize:
 				// Files with same name and size must be checked for content equality.
 				// True duplicates (identical content) can be safely merged by keeping one of the files.
 				// Different content with same name/size indicates a conflict we can't resolve automatically.
-				isSameHash, err := areFileHashesIdentical(existingFile, currentFile)
+				isSameHash, err := r.areFileHashesIdentical(existingFile, currentFile)
 				if err != nil {
-					return nil, fmt.Errorf("failed comparing files with name \"%s\": %w", baseName, err)
+					return nil, &InputError{Err: fmt.Errorf("failed comparing files with name \"%s\": %w", flattenedName, err)}
 				}
 				if !isSameHash {
-					return nil, fmt.Errorf("files with name \"%s\" have identical sizes but differing content (paths: %s and %s)",
-						baseName, existingFile.Name, currentFile.Name)
+					return nil, &ConflictError{Err: fmt.Errorf("files with name \"%s\" have identical sizes but differing content (paths: %s and %s)",
+						flattenedName, r.entryPath(existingFile), r.entryPath(currentFile))}
 				}
+				droppedPaths[duplicateKey] = append(droppedPaths[duplicateKey], r.entryPath(currentFile))
+				r.logDuplicate(flattenedName, existingFile, currentFile, "identical content")
 			case currentSize > existingSize:
-				deduplicatedFiles[baseName] = currentFile
+				delete(deduplicatedFiles, existingName)
+				deduplicatedFiles[flattenedName] = currentFile
+				keptNames[duplicateKey] = flattenedName
+				droppedPaths[duplicateKey] = append(droppedPaths[duplicateKey], r.entryPath(existingFile))
+				r.logDuplicate(flattenedName, currentFile, existingFile, "larger")
+			default:
+				droppedPaths[duplicateKey] = append(droppedPaths[duplicateKey], r.entryPath(currentFile))
+				r.logDuplicate(existingName, existingFile, currentFile, "larger")
 			}
 		} else {
-			deduplicatedFiles[baseName] = currentFile
+			deduplicatedFiles[flattenedName] = currentFile
+			keptNames[duplicateKey] = flattenedName
+			r.logger.Debug("flattened entry", "path", r.entryPath(currentFile), "name", flattenedName, "size", currentSize)
 		}
 	}
 
+	r.completeEntries(len(files))
+
+	for duplicateKey, paths := range droppedPaths {
+		r.duplicates[keptNames[duplicateKey]] = paths
+	}
+
 	return deduplicatedFiles, nil
 }
 
+// logDuplicate logs the entry dropped in favor of the kept entry with the same flattened name,
+// and why the kept entry won.
+func (r *repackager) logDuplicate(name string, keptFile, droppedFile *zip.File, reason string) {
+	r.logger.Info("dropped duplicate", "name", name, "kept", r.entryPath(keptFile), "dropped", r.entryPath(droppedFile), "reason", reason)
+}
+
+// skippedEntries lists the symlinks and metadata files found while flattening along with the
+// duplicates dropped in favor of the files of the output registry, sorted by path.
+func (r *repackager) skippedEntries(outputFileRegistry map[string]FileInfo) []SkippedEntry {
+	skipped := append([]SkippedEntry(nil), r.skipped...)
+
+	for name, info := range outputFileRegistry {
+		for _, duplicatePath := range info.Duplicates {
+			skipped = append(skipped, SkippedEntry{Path: duplicatePath, Reason: SkipReasonDuplicate, KeptAs: name})
+		}
+		for _, alias := range info.Aliases {
+			skipped = append(skipped, SkippedEntry{Path: alias.OriginalPath, Reason: SkipReasonDuplicateContent, KeptAs: name})
+		}
+	}
+
+	slices.SortFunc(skipped, func(a, b SkippedEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return skipped
+}
+
+// duplicateKey returns the key under which entries with the given flattened name are deduplicated.
+func (r *repackager) duplicateKey(flattenedName string) string {
+	if r.options.CaseInsensitive {
+		return foldCase(flattenedName)
+	}
+	return flattenedName
+}
+
+// deduplicateContent removes the files kept by name that have the same content as another kept
+// file, keeping the canonical one according to the options and recording the others as its aliases.
+// The files of the input archive are passed to process the kept files in archive order.
+func (r *repackager) deduplicateContent(ctx context.Context, deduplicatedFiles map[string]*zip.File, files []*zip.File) error {
+	namesByFile := make(map[*zip.File]string, len(deduplicatedFiles))
+	for name, file := range deduplicatedFiles {
+		namesByFile[file] = name
+	}
+
+	// Only files sharing their size can have identical content, so other files are never hashed.
+	filesBySize := make(map[int64][]*zip.File)
+	for _, file := range files {
+		if _, isKept := namesByFile[file]; !isKept {
+			continue
+		}
+
+		size, err := r.sizeOf(file)
+		if err != nil {
+			return &InputError{Err: fmt.Errorf("failed measuring file \"%s\": %w", r.entryPath(file), err)}
+		}
+		filesBySize[size] = append(filesBySize[size], file)
+	}
+
+	for _, sameSizeFiles := range filesBySize {
+		if len(sameSizeFiles) < 2 {
+			continue
+		}
+
+		filesByHash := make(map[Digest][]*zip.File, len(sameSizeFiles))
+		for _, file := range sameSizeFiles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			fileHash, err := r.hashOf(file)
+			if err != nil {
+				return &InputError{Err: fmt.Errorf("failed hashing file \"%s\": %w", r.entryPath(file), err)}
+			}
+			filesByHash[fileHash] = append(filesByHash[fileHash], file)
+		}
+
+		for _, sameHashFiles := range filesByHash {
+			if len(sameHashFiles) < 2 {
+				continue
+			}
+
+			groups, err := r.groupByContent(sameHashFiles)
+			if err != nil {
+				return &InputError{Err: err}
+			}
+			for _, identicalFiles := range groups {
+				if len(identicalFiles) >= 2 {
+					r.dropDuplicateContent(deduplicatedFiles, identicalFiles, namesByFile)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// dropDuplicateContent keeps the canonical file among files with identical content, given in
+// archive order, and records the others as its aliases.
+func (r *repackager) dropDuplicateContent(deduplicatedFiles map[string]*zip.File, identicalFiles []*zip.File,
+	namesByFile map[*zip.File]string) {
+	canonicalName := namesByFile[r.canonicalFile(identicalFiles, namesByFile)]
+	for _, file := range identicalFiles {
+		name := namesByFile[file]
+		if name == canonicalName {
+			continue
+		}
+
+		delete(deduplicatedFiles, name)
+		r.logger.Info("dropped duplicate content", "name", canonicalName,
+			"kept", r.entryPath(deduplicatedFiles[canonicalName]), "dropped", r.entryPath(file))
+		r.aliases[canonicalName] = append(r.aliases[canonicalName], Alias{
+			Name:         name,
+			OriginalPath: r.entryPath(file),
+		})
+
+		// Files dropped in favor of the alias are now replaced by the canonical file.
+		if aliasDuplicates, hasDuplicates := r.duplicates[name]; hasDuplicates {
+			r.duplicates[canonicalName] = append(r.duplicates[canonicalName], aliasDuplicates...)
+			delete(r.duplicates, name)
+		}
+	}
+}
+
+// canonicalFile picks the file to keep among files with identical content, given in archive order.
+func (r *repackager) canonicalFile(identicalFiles []*zip.File, namesByFile map[*zip.File]string) *zip.File {
+	canonical := identicalFiles[0]
+	for _, file := range identicalFiles[1:] {
+		name, canonicalName := namesByFile[file], namesByFile[canonical]
+
+		switch r.options.CanonicalRule {
+		case CanonicalShortest:
+			if len(name) < len(canonicalName) || len(name) == len(canonicalName) && name < canonicalName {
+				canonical = file
+			}
+		case CanonicalLexical:
+			if name < canonicalName {
+				canonical = file
+			}
+		}
+	}
+	return canonical
+}
+
 // createOutputZip builds an uncompressed ZIP archive from deduplicated files,
 // storing their original paths and content hashes for validation purposes.
-func createOutputZip(deduplicatedFiles map[string]*zip.File, outputPath string) (map[string]FileInfo, error) {
-	outputFile, err := os.Create(outputPath)
+func (r *repackager) createOutputZip(ctx context.Context, deduplicatedFiles map[string]*zip.File,
+	outputPath string) (map[string]FileInfo, error) {
+	outputFile, err := r.createOutputFile(outputPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create output file: %w", err)
+		return nil, &OutputError{Err: fmt.Errorf("failed to create output file: %w", err)}
 	}
 	defer outputFile.Close()
 
+	// Remove the temporary output file unless it replaced the output.
+	isCommitted := false
+	defer func() {
+		if !isCommitted && outputFile.Name() != outputPath {
+			os.Remove(outputFile.Name())
+		}
+	}()
+
 	zipWriter := zip.NewWriter(outputFile)
 	defer zipWriter.Close()
 
 	outputFileRegistry := make(map[string]FileInfo, len(deduplicatedFiles))
 
-	for baseName, zipEntry := range deduplicatedFiles {
-		fileHash, err := writeAndHashEntry(zipWriter, zipEntry, baseName)
+	// Files are written in name order, so that the output only depends on the input.
+	r.startPhase(ProgressWriting, len(deduplicatedFiles))
+	for _, baseName := range slices.Sorted(maps.Keys(deduplicatedFiles)) {
+		zipEntry := deduplicatedFiles[baseName]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entryMeasurement, err := r.writeAndHashEntry(zipWriter, zipEntry, baseName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to write and hash file in output zip with name \"%s\": %w", baseName, err)
+			return nil, &OutputError{Err: fmt.Errorf("failed to write and hash file in output zip with name \"%s\": %w", baseName, err)}
 		}
 
+		r.logger.Debug("wrote entry", "name", baseName, "path", r.entryPath(zipEntry), "size", entryMeasurement.size)
+
 		outputFileRegistry[baseName] = FileInfo{
-			OriginalPath: zipEntry.Name,
-			Hash:         fileHash,
+			OriginalPath:  r.entryPath(zipEntry),
+			HashAlgorithm: r.algorithm,
+			Hash:          entryMeasurement.hash,
+			Size:          entryMeasurement.size,
+			Duplicates:    r.duplicates[baseName],
+			Aliases:       r.aliases[baseName],
 		}
+		r.completeEntries(len(outputFileRegistry))
+	}
+
+	if r.options.EmbedManifest {
+		if err := r.writeManifest(zipWriter, outputFileRegistry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.commitOutputFile(zipWriter, outputFile, outputPath); err != nil {
+		return nil, &OutputError{Err: fmt.Errorf("failed to write output file: %w", err)}
 	}
+	isCommitted = true
 
+	r.logger.Info("created output archive", "path", outputPath, "files", len(outputFileRegistry))
 	return outputFileRegistry, nil
 }
@@ -3,9 +3,15 @@ package repackage
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/json"
+	"hash/crc32"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -20,10 +26,12 @@ func TestRun(t *testing.T) {
 		inputPath := filepath.Join(tempDir, "nonexistent.zip")
 		outputPath := filepath.Join(tempDir, "output.zip")
 
-		_, err := Run(inputPath, outputPath)
+		_, err := Run(context.Background(), []string{inputPath}, outputPath, Options{})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to open input zip")
+		var inputErr *InputError
+		assert.ErrorAs(t, err, &inputErr)
 	})
 
 	t.Run("Returns error when flatten and deduplication fails", func(t *testing.T) {
@@ -39,10 +47,12 @@ func TestRun(t *testing.T) {
 		err := makeTestZip(inputPath, entries)
 		require.NoError(t, err, "Failed to create test ZIP file")
 
-		_, err = Run(inputPath, outputPath)
+		_, err = Run(context.Background(), []string{inputPath}, outputPath, Options{})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "identical sizes but differing content")
+		var conflictErr *ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
 	})
 
 	t.Run("Returns error when output ZIP cannot be created", func(t *testing.T) {
@@ -55,10 +65,26 @@ func TestRun(t *testing.T) {
 		nonExistentDir := filepath.Join(tempDir, "nonexistent")
 		outputPath := filepath.Join(nonExistentDir, "output.zip")
 
-		_, err = Run(inputPath, outputPath)
+		_, err = Run(context.Background(), []string{inputPath}, outputPath, Options{})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create output file")
+		var outputErr *OutputError
+		assert.ErrorAs(t, err, &outputErr)
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "cancelled_input.zip")
+		err := makeTestZip(inputPath, map[string]string{"test.txt": "content"})
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := Run(ctx, []string{inputPath}, filepath.Join(tempDir, "cancelled_output.zip"), Options{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, result)
 	})
 
 	t.Run("Successfully repackages ZIP", func(t *testing.T) {
@@ -73,14 +99,14 @@ func TestRun(t *testing.T) {
 		err := makeTestZip(inputPath, entries)
 		require.NoError(t, err, "Failed to create test ZIP file")
 
-		result, err := Run(inputPath, outputPath)
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{})
 
 		assert.NoError(t, err)
-		assert.Len(t, result, 2, "Expected 2 files in output")
-		assert.Contains(t, result, "file1.txt")
-		assert.Contains(t, result, "file2.txt")
-		assert.Equal(t, "foo/bar/file1.txt", result["file1.txt"].OriginalPath)
-		assert.Equal(t, "dir/file2.txt", result["file2.txt"].OriginalPath)
+		assert.Len(t, result.Files, 2, "Expected 2 files in output")
+		assert.Contains(t, result.Files, "file1.txt")
+		assert.Contains(t, result.Files, "file2.txt")
+		assert.Equal(t, "foo/bar/file1.txt", result.Files["file1.txt"].OriginalPath)
+		assert.Equal(t, "dir/file2.txt", result.Files["file2.txt"].OriginalPath)
 
 		// Verify output ZIP exists and is readable.
 		zipReader, err := zip.OpenReader(outputPath)
@@ -95,6 +121,40 @@ func TestRun(t *testing.T) {
 		assertZipHasExpectedContent(t, outputPath, "file2.txt", "content2")
 	})
 
+	t.Run("Successfully logs skipped, dropped and written entries", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "logged_input.zip")
+		outputPath := filepath.Join(tempDir, "logged_output.zip")
+		err := makeTestZip(inputPath, map[string]string{
+			"a/foo.txt":  "small",
+			"b/foo.txt":  "larger content",
+			".DS_Store":  "metadata",
+			"docs/a.txt": "a",
+		})
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		_, err = Run(context.Background(), []string{inputPath}, outputPath, Options{Logger: logger})
+		require.NoError(t, err)
+
+		var events []map[string]any
+		decoder := json.NewDecoder(&logs)
+		for decoder.More() {
+			var event map[string]any
+			require.NoError(t, decoder.Decode(&event))
+			delete(event, "time")
+			events = append(events, event)
+		}
+
+		assert.Contains(t, events, map[string]any{"level": "INFO", "msg": "skipped metadata file", "path": ".DS_Store"})
+		assert.Contains(t, events, map[string]any{
+			"level": "INFO", "msg": "dropped duplicate", "name": "foo.txt", "kept": "b/foo.txt", "dropped": "a/foo.txt", "reason": "larger",
+		})
+		assert.Contains(t, events, map[string]any{"level": "DEBUG", "msg": "wrote entry", "name": "a.txt", "path": "docs/a.txt", "size": float64(1)})
+		assert.Contains(t, events, map[string]any{"level": "INFO", "msg": "created output archive", "path": outputPath, "files": float64(2)})
+	})
+
 	t.Run("End-to-end test with all features", func(t *testing.T) {
 		inputPath := filepath.Join(tempDir, "endtoend_input.zip")
 		outputPath := filepath.Join(tempDir, "endtoend_output.zip")
@@ -110,18 +170,197 @@ func TestRun(t *testing.T) {
 		err := makeTestZip(inputPath, entries)
 		require.NoError(t, err, "Failed to create test ZIP file")
 
-		result, err := Run(inputPath, outputPath)
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{})
 
 		assert.NoError(t, err)
-		assert.Len(t, result, 2, "Should have 2 files after processing")
-		assert.Contains(t, result, "foo.txt")
-		assert.Contains(t, result, "bar.txt")
-		assert.Equal(t, "b/foo.txt", result["foo.txt"].OriginalPath)
-		assert.Equal(t, "deep/nested/bar.txt", result["bar.txt"].OriginalPath)
+		assert.Len(t, result.Files, 2, "Should have 2 files after processing")
+		assert.Contains(t, result.Files, "foo.txt")
+		assert.Contains(t, result.Files, "bar.txt")
+		assert.Equal(t, "b/foo.txt", result.Files["foo.txt"].OriginalPath)
+		assert.Equal(t, "deep/nested/bar.txt", result.Files["bar.txt"].OriginalPath)
 
 		assertZipHasExpectedContent(t, outputPath, "foo.txt", "larger content")
 		assertZipHasExpectedContent(t, outputPath, "bar.txt", "test content")
 	})
+
+	t.Run("Successfully lists skipped entries with their reasons", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "skipped_input.zip")
+		outputPath := filepath.Join(tempDir, "skipped_output.zip")
+
+		entries := map[string]string{
+			"a/foo.txt":           "small",
+			"b/foo.txt":           "larger content",
+			"c/foo-copy.txt":      "larger content",
+			"__MACOSX/ignore.txt": "metadata",
+			".DS_Store":           "more metadata",
+		}
+		err := makeTestZip(inputPath, entries)
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{DedupeContent: true, CanonicalRule: CanonicalShortest})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []SkippedEntry{
+			{Path: ".DS_Store", Reason: SkipReasonMetadata},
+			{Path: "__MACOSX/ignore.txt", Reason: SkipReasonMetadata},
+			{Path: "a/foo.txt", Reason: SkipReasonDuplicate, KeptAs: "foo.txt"},
+			{Path: "c/foo-copy.txt", Reason: SkipReasonDuplicateContent, KeptAs: "foo.txt"},
+		}, result.Skipped)
+	})
+
+	t.Run("Successfully repackages entries with wrong declared sizes when sizes are verified", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "lying_sizes_input.zip")
+		outputPath := filepath.Join(tempDir, "lying_sizes_output.zip")
+
+		file, err := os.Create(inputPath)
+		require.NoError(t, err)
+		zipWriter := zip.NewWriter(file)
+		writeTestZipEntryWithDeclaredSize(t, zipWriter, "a/foo.txt", "actually the larger content", 1)
+		writeTestZipEntryWithDeclaredSize(t, zipWriter, "b/foo.txt", "small", 5)
+		require.NoError(t, zipWriter.Close())
+		require.NoError(t, file.Close())
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{VerifySizes: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "a/foo.txt", result.Files["foo.txt"].OriginalPath)
+		assert.Equal(t, []SizeMismatch{{Path: "a/foo.txt", DeclaredSize: 1, ActualSize: 27}}, result.SizeMismatches)
+		assertZipHasExpectedContent(t, outputPath, "foo.txt", "actually the larger content")
+	})
+
+	t.Run("Successfully reports names differing by case when collisions are warned about", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "case_input.zip")
+		outputPath := filepath.Join(tempDir, "case_output.zip")
+
+		entries := map[string]string{
+			"docs/README.md": "docs",
+			"src/readme.md":  "source readme",
+			"src/main.go":    "package main",
+		}
+		err := makeTestZip(inputPath, entries)
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{WarnCaseCollisions: true})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Files, 3, "Colliding names should be kept")
+		assert.Equal(t, [][]string{{"README.md", "readme.md"}}, result.CaseCollisions)
+		assert.Equal(t, []string{"readme.md"}, result.Files["README.md"].CaseCollisions)
+		assert.Equal(t, []string{"README.md"}, result.Files["readme.md"].CaseCollisions)
+		assert.Empty(t, result.Files["main.go"].CaseCollisions)
+	})
+
+	t.Run("Successfully records aliases when deduplicating content", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "content_input.zip")
+		outputPath := filepath.Join(tempDir, "content_output.zip")
+
+		entries := map[string]string{
+			"img/logo.png":      "png bytes",
+			"copy/logo-old.png": "png bytes",
+			"doc/readme.txt":    "readme",
+		}
+		err := makeTestZip(inputPath, entries)
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{DedupeContent: true, CanonicalRule: CanonicalLexical})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Files, 2)
+		assert.Equal(t, []Alias{{Name: "logo.png", OriginalPath: "img/logo.png"}}, result.Files["logo-old.png"].Aliases)
+		assert.Equal(t, int64(len("png bytes")), result.Files["logo-old.png"].Size)
+		assert.Empty(t, result.Files["readme.txt"].Aliases)
+		assertZipHasExpectedContent(t, outputPath, "logo-old.png", "png bytes")
+	})
+
+	t.Run("Successfully hashes each duplicated entry once", func(t *testing.T) {
+		inputPath := filepath.Join(tempDir, "cache_input.zip")
+		outputPath := filepath.Join(tempDir, "cache_output.zip")
+
+		entries := map[string]string{
+			"a/same.txt": "same content",
+			"b/same.txt": "same content",
+			"c/same.txt": "same content",
+		}
+		err := makeTestZip(inputPath, entries)
+		require.NoError(t, err, "Failed to create test ZIP file")
+
+		hashCache := NewHashCache()
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{HashCache: hashCache})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Files, 1)
+		assert.Equal(t, sha256Digest("same content"), result.Files["same.txt"].Hash)
+
+		// Each entry is read once; the kept entry is then compared and written from the cache.
+		assert.Equal(t, HashCacheStats{Hits: 2, Misses: 3}, hashCache.Stats())
+	})
+
+	t.Run("Returns error without input archives", func(t *testing.T) {
+		result, err := Run(context.Background(), nil, filepath.Join(tempDir, "no_input_output.zip"), Options{})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "no input zip to repackage")
+		var inputErr *InputError
+		assert.ErrorAs(t, err, &inputErr)
+	})
+
+	t.Run("Returns error when merged archives conflict", func(t *testing.T) {
+		firstPath := filepath.Join(tempDir, "merge_conflict_first.zip")
+		secondPath := filepath.Join(tempDir, "merge_conflict_second.zip")
+		require.NoError(t, makeTestZip(firstPath, map[string]string{"lib/conflict.txt": "content1"}))
+		require.NoError(t, makeTestZip(secondPath, map[string]string{"lib/conflict.txt": "content2"}))
+
+		_, err := Run(context.Background(), []string{firstPath, secondPath}, filepath.Join(tempDir, "merge_conflict_output.zip"), Options{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), firstPath+"!/lib/conflict.txt and "+secondPath+"!/lib/conflict.txt")
+		var conflictErr *ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+	})
+
+	t.Run("Successfully merges archives into a single namespace", func(t *testing.T) {
+		firstPath := filepath.Join(tempDir, "merge_first.zip")
+		secondPath := filepath.Join(tempDir, "merge_second.zip")
+		outputPath := filepath.Join(tempDir, "merge_output.zip")
+		require.NoError(t, makeTestZip(firstPath, map[string]string{
+			"lib/shared.txt": "shared",
+			"docs/notes.txt": "short",
+		}))
+		require.NoError(t, makeTestZip(secondPath, map[string]string{
+			"lib/shared.txt":   "shared",
+			"other/notes.txt":  "longer notes",
+			"vendor/extra.txt": "extra",
+			".DS_Store":        "metadata",
+		}))
+
+		result, err := Run(context.Background(), []string{firstPath, secondPath}, outputPath, Options{})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Files, 3)
+		assert.Equal(t, firstPath+"!/lib/shared.txt", result.Files["shared.txt"].OriginalPath)
+		assert.Equal(t, []string{secondPath + "!/lib/shared.txt"}, result.Files["shared.txt"].Duplicates)
+		assert.Equal(t, secondPath+"!/other/notes.txt", result.Files["notes.txt"].OriginalPath)
+		assert.Equal(t, secondPath+"!/vendor/extra.txt", result.Files["extra.txt"].OriginalPath)
+		assert.ElementsMatch(t, []SkippedEntry{
+			{Path: firstPath + "!/docs/notes.txt", Reason: SkipReasonDuplicate, KeptAs: "notes.txt"},
+			{Path: secondPath + "!/lib/shared.txt", Reason: SkipReasonDuplicate, KeptAs: "shared.txt"},
+			{Path: secondPath + "!/.DS_Store", Reason: SkipReasonMetadata},
+		}, result.Skipped)
+
+		assertZipHasExpectedContent(t, outputPath, "notes.txt", "longer notes")
+		assertZipHasExpectedContent(t, outputPath, "extra.txt", "extra")
+	})
+}
+
+func TestEntryPath(t *testing.T) {
+	t.Run("Successfully keeps entry names of a single archive", func(t *testing.T) {
+		assert.Equal(t, "lib/a.txt", EntryPath("vendor.zip", "lib/a.txt", false))
+	})
+
+	t.Run("Successfully prefixes entry names of merged archives with their archive", func(t *testing.T) {
+		assert.Equal(t, "vendor.zip!/lib/a.txt", EntryPath("vendor.zip", "lib/a.txt", true))
+	})
 }
 
 func TestFlattenAndDeduplicate(t *testing.T) {
@@ -134,7 +373,7 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		badFile := makeCorruptedZipFile(t, "dir2/file.txt", []byte("some content"))
 
 		files := []*zip.File{goodFile, badFile}
-		deduped, err := flattenAndDeduplicate(files)
+		deduped, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), files)
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(),
@@ -147,7 +386,7 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		file1 := createTestZipFile("dir1/file.txt", "content1")
 		file2 := createTestZipFile("dir2/file.txt", "content2")
 
-		_, err := flattenAndDeduplicate([]*zip.File{file1, file2})
+		_, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), []*zip.File{file1, file2})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "identical sizes but differing content")
@@ -158,7 +397,7 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		fileEntry := createTestZipFile("dir/file.txt", "content")
 		dirEntry := createTestZipDir("dir/")
 
-		result, err := flattenAndDeduplicate([]*zip.File{fileEntry, dirEntry})
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), []*zip.File{fileEntry, dirEntry})
 
 		assert.NoError(t, err)
 		assert.Len(t, result, 1, "Expected only the file entry")
@@ -171,7 +410,7 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		regularFile := createTestZipFile("dir/file.txt", "content")
 		symlinkFile := createTestZipSymlink("dir/symlink.txt", "target.txt")
 
-		result, err := flattenAndDeduplicate([]*zip.File{regularFile, symlinkFile})
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), []*zip.File{regularFile, symlinkFile})
 
 		assert.NoError(t, err)
 		assert.Len(t, result, 1, "Expected only the regular file")
@@ -186,8 +425,8 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		dsStoreFile := createTestZipFile(".DS_Store", "metadata")
 		thumbsFile := createTestZipFile("Thumbs.db", "windows metadata")
 
-		result, err := flattenAndDeduplicate(
-			[]*zip.File{regularFile, macosxFile, dsStoreFile, thumbsFile},
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{regularFile, macosxFile, dsStoreFile, thumbsFile},
 		)
 
 		assert.NoError(t, err)
@@ -198,18 +437,161 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 		assert.NotContains(t, result, "Thumbs.db", "Windows metadata file should be skipped")
 	})
 
+	t.Run("Successfully records skipped symlinks and metadata files", func(t *testing.T) {
+		regularFile := createTestZipFile("dir/file.txt", "content")
+		symlinkFile := createTestZipFileWithMode("dir/link.txt", "file.txt", os.ModeSymlink|0777)
+		dsStoreFile := createTestZipFile("dir/.DS_Store", "metadata")
+
+		r := newRepackager(Options{})
+		_, err := r.flattenAndDeduplicate(context.Background(), []*zip.File{regularFile, symlinkFile, dsStoreFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []SkippedEntry{
+			{Path: "dir/link.txt", Reason: SkipReasonSymlink},
+			{Path: "dir/.DS_Store", Reason: SkipReasonMetadata},
+		}, r.skipped)
+	})
+
 	t.Run("Successfully keeps larger file when deduplicating", func(t *testing.T) {
 		// Create two files with same name but different sizes.
 		smallFile := createTestZipFile("dir1/file.txt", "small")
 		largeFile := createTestZipFile("dir2/file.txt", "larger content")
 
-		result, err := flattenAndDeduplicate([]*zip.File{smallFile, largeFile})
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), []*zip.File{smallFile, largeFile})
 
 		assert.NoError(t, err)
 		assert.Len(t, result, 1, "Expected 1 file after deduplication")
 		assert.Equal(t, largeFile, result["file.txt"], "Larger file should be kept")
 	})
 
+	t.Run("Successfully records the paths of dropped duplicates", func(t *testing.T) {
+		smallFile := createTestZipFile("dir1/file.txt", "small")
+		largeFile := createTestZipFile("dir2/file.txt", "larger content")
+		sameFile := createTestZipFile("dir3/file.txt", "larger content")
+		smallerFile := createTestZipFile("dir4/file.txt", "tiny")
+		otherFile := createTestZipFile("dir1/other.txt", "other")
+
+		r := newRepackager(Options{})
+		_, err := r.flattenAndDeduplicate(context.Background(), []*zip.File{smallFile, largeFile, sameFile, smallerFile, otherFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"file.txt": {"dir1/file.txt", "dir3/file.txt", "dir4/file.txt"},
+		}, r.duplicates)
+	})
+
+	t.Run("Successfully keeps the declared larger file when sizes are not verified", func(t *testing.T) {
+		lyingFile := createTestZipFileWithDeclaredSize(t, "dir1/file.txt", "much larger content", 1)
+		honestFile := createTestZipFileWithDeclaredSize(t, "dir2/file.txt", "small", 5)
+
+		r := newRepackager(Options{})
+		result, err := r.flattenAndDeduplicate(context.Background(), []*zip.File{lyingFile, honestFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, honestFile, result["file.txt"], "Declared sizes should be trusted")
+		assert.Empty(t, r.sizeMismatches)
+	})
+
+	t.Run("Successfully keeps the actually larger file when sizes are verified", func(t *testing.T) {
+		lyingFile := createTestZipFileWithDeclaredSize(t, "dir1/file.txt", "much larger content", 1)
+		honestFile := createTestZipFileWithDeclaredSize(t, "dir2/file.txt", "small", 5)
+
+		r := newRepackager(Options{VerifySizes: true})
+		result, err := r.flattenAndDeduplicate(context.Background(), []*zip.File{lyingFile, honestFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, lyingFile, result["file.txt"], "Measured sizes should be used")
+		require.Len(t, r.sizeMismatches, 1)
+		assert.Equal(t, SizeMismatch{Path: "dir1/file.txt", DeclaredSize: 1, ActualSize: 19}, r.sizeMismatches[0])
+	})
+
+	t.Run("Returns error when a verified entry fails its checksum", func(t *testing.T) {
+		file := createTestZipFileWithDeclaredSize(t, "dir/file.txt", "content", 7)
+		file.CRC32++
+
+		_, err := newRepackager(Options{VerifySizes: true}).flattenAndDeduplicate(context.Background(), []*zip.File{file})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `failed measuring file "dir/file.txt"`)
+	})
+
+	t.Run("Successfully deduplicates entries whose sanitized names collide", func(t *testing.T) {
+		colonFile := createTestZipFile("dir1/a:b.txt", "small")
+		backslashFile := createTestZipFile(`dir2\a_b.txt`, "larger content")
+
+		result, err := newRepackager(Options{NamePolicy: NamePolicyWindows}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{colonFile, backslashFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 1, "Sanitized names should be deduplicated")
+		assert.Equal(t, backslashFile, result["a_b.txt"], "Larger file should be kept")
+	})
+
+	t.Run("Successfully keeps names differing by case when case is significant", func(t *testing.T) {
+		upperFile := createTestZipFile("docs/README.md", "small")
+		lowerFile := createTestZipFile("src/readme.md", "larger content")
+
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), []*zip.File{upperFile, lowerFile})
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+	})
+
+	t.Run("Successfully deduplicates names differing by case when case is ignored", func(t *testing.T) {
+		upperFile := createTestZipFile("docs/README.md", "small")
+		lowerFile := createTestZipFile("src/readme.md", "larger content")
+		sameFile := createTestZipFile("other/Readme.md", "small")
+
+		result, err := newRepackager(Options{CaseInsensitive: true}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{upperFile, sameFile, lowerFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 1, "Expected 1 file after case-insensitive deduplication")
+		assert.Equal(t, lowerFile, result["readme.md"], "Larger file should be kept under its own name")
+	})
+
+	t.Run("Returns error when names differing by case have same size but different content", func(t *testing.T) {
+		upperFile := createTestZipFile("docs/README.md", "content1")
+		lowerFile := createTestZipFile("src/readme.md", "content2")
+
+		_, err := newRepackager(Options{CaseInsensitive: true}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{upperFile, lowerFile},
+		)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "identical sizes but differing content")
+	})
+
+	t.Run("Successfully deduplicates names differing by Unicode normalization", func(t *testing.T) {
+		composedFile := createTestZipFile("linux/caf\u00e9.txt", "small")
+		decomposedFile := createTestZipFile("macos/cafe\u0301.txt", "larger content")
+
+		result, err := newRepackager(Options{Normalization: NormalizationNFC}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{composedFile, decomposedFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 1, "Normalized names should be deduplicated")
+		assert.Equal(t, decomposedFile, result["caf\u00e9.txt"], "Larger file should be kept")
+	})
+
+	t.Run("Successfully deduplicates relative paths when keeping path components", func(t *testing.T) {
+		englishFile := createTestZipFile("v1/lang/en/strings.json", "english")
+		frenchFile := createTestZipFile("v1/lang/fr/strings.json", "french")
+		newerFrenchFile := createTestZipFile("v2/lang/fr/strings.json", "french, updated")
+
+		result, err := newRepackager(Options{KeepDepth: 2}).flattenAndDeduplicate(
+			context.Background(), []*zip.File{englishFile, frenchFile, newerFrenchFile},
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, englishFile, result["en/strings.json"])
+		assert.Equal(t, newerFrenchFile, result["fr/strings.json"], "Larger file should be kept")
+	})
+
 	t.Run("Successfully handles combination of all cases", func(t *testing.T) {
 		// Create a comprehensive test with all types of entries.
 		entries := []*zip.File{
@@ -232,7 +614,7 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 			createTestZipFile("another/small.txt", "larger content"),
 		}
 
-		result, err := flattenAndDeduplicate(entries)
+		result, err := newRepackager(Options{}).flattenAndDeduplicate(context.Background(), entries)
 
 		assert.NoError(t, err)
 		assert.Len(t, result, 3, "Expected 3 files after processing")
@@ -250,6 +632,100 @@ func TestFlattenAndDeduplicate(t *testing.T) {
 	})
 }
 
+func TestParseCanonicalRule(t *testing.T) {
+	t.Run("Returns error for unknown rule", func(t *testing.T) {
+		_, err := ParseCanonicalRule("longest")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown canonical rule "longest"`)
+	})
+
+	t.Run("Successfully parses all rules", func(t *testing.T) {
+		for _, value := range []string{"first", "shortest", "lexical"} {
+			rule, err := ParseCanonicalRule(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, CanonicalRule(value), rule)
+		}
+	})
+}
+
+func TestDeduplicateContent(t *testing.T) {
+	// newFiles returns kept files in archive order and the map of kept files by name.
+	newFiles := func() ([]*zip.File, map[string]*zip.File) {
+		files := []*zip.File{
+			createTestZipFile("img/logo.png", "png bytes"),
+			createTestZipFile("img/logo-copy.png", "png bytes"),
+			createTestZipFile("img/a.png", "png bytes"),
+			createTestZipFile("img/other.png", "other png"),
+			createTestZipFile("doc/readme.txt", "different size"),
+		}
+		deduplicatedFiles := make(map[string]*zip.File, len(files))
+		for _, file := range files {
+			deduplicatedFiles[filepath.Base(file.Name)] = file
+		}
+		return files, deduplicatedFiles
+	}
+
+	t.Run("Successfully keeps the first file by default", func(t *testing.T) {
+		files, deduplicatedFiles := newFiles()
+		r := newRepackager(Options{DedupeContent: true})
+
+		err := r.deduplicateContent(context.Background(), deduplicatedFiles, files)
+
+		assert.NoError(t, err)
+		assert.Len(t, deduplicatedFiles, 3)
+		assert.Contains(t, deduplicatedFiles, "logo.png")
+		assert.Contains(t, deduplicatedFiles, "other.png", "Different content of the same size should be kept")
+		assert.Contains(t, deduplicatedFiles, "readme.txt")
+		assert.Equal(t, []Alias{
+			{Name: "logo-copy.png", OriginalPath: "img/logo-copy.png"},
+			{Name: "a.png", OriginalPath: "img/a.png"},
+		}, r.aliases["logo.png"])
+	})
+
+	t.Run("Successfully keeps the shortest name", func(t *testing.T) {
+		files, deduplicatedFiles := newFiles()
+		r := newRepackager(Options{DedupeContent: true, CanonicalRule: CanonicalShortest})
+
+		err := r.deduplicateContent(context.Background(), deduplicatedFiles, files)
+
+		assert.NoError(t, err)
+		assert.Contains(t, deduplicatedFiles, "a.png")
+		assert.NotContains(t, deduplicatedFiles, "logo.png")
+		assert.Len(t, r.aliases["a.png"], 2)
+	})
+
+	t.Run("Successfully keeps the lexicographically smallest name", func(t *testing.T) {
+		files, deduplicatedFiles := newFiles()
+		deduplicatedFiles["a.png"] = files[3]
+		deduplicatedFiles["other.png"] = files[2]
+		r := newRepackager(Options{DedupeContent: true, CanonicalRule: CanonicalLexical})
+
+		err := r.deduplicateContent(context.Background(), deduplicatedFiles, files)
+
+		assert.NoError(t, err)
+		assert.Contains(t, deduplicatedFiles, "logo-copy.png")
+		assert.Equal(t, []Alias{
+			{Name: "logo.png", OriginalPath: "img/logo.png"},
+			{Name: "other.png", OriginalPath: "img/a.png"},
+		}, r.aliases["logo-copy.png"])
+	})
+
+	t.Run("Returns error when hashing a file fails", func(t *testing.T) {
+		goodFile := createTestZipFile("dir1/good.txt", "some content")
+		badFile := makeCorruptedZipFile(t, "dir2/bad.txt", []byte("some content"))
+		deduplicatedFiles := map[string]*zip.File{"good.txt": goodFile, "bad.txt": badFile}
+
+		err := newRepackager(Options{DedupeContent: true}).deduplicateContent(
+			context.Background(), deduplicatedFiles, []*zip.File{goodFile, badFile},
+		)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `failed hashing file "dir2/bad.txt"`)
+	})
+}
+
 func TestCreateOutputZip(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -273,7 +749,7 @@ func TestCreateOutputZip(t *testing.T) {
 			deduplicatedFiles[file.Name] = file
 		}
 
-		fileRegistry, err := createOutputZip(deduplicatedFiles, outputPath)
+		fileRegistry, err := newRepackager(Options{}).createOutputZip(context.Background(), deduplicatedFiles, outputPath)
 
 		assert.NoError(t, err)
 		assert.Len(t, fileRegistry, 2, "Should have metadata for 2 files")
@@ -297,7 +773,7 @@ func TestCreateOutputZip(t *testing.T) {
 		// Try to create output in a non-existent directory.
 		nonExistentPath := filepath.Join(tempDir, "nonexistent", "output.zip")
 
-		_, err := createOutputZip(map[string]*zip.File{}, nonExistentPath)
+		_, err := newRepackager(Options{}).createOutputZip(context.Background(), map[string]*zip.File{}, nonExistentPath)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create output file")
@@ -312,7 +788,7 @@ func TestReadZipFileContent(t *testing.T) {
 		nonExistentDir := filepath.Join(tempDir, "non-existent")
 		outputPath := filepath.Join(nonExistentDir, "output.zip")
 
-		_, err := createOutputZip(map[string]*zip.File{}, outputPath)
+		_, err := newRepackager(Options{}).createOutputZip(context.Background(), map[string]*zip.File{}, outputPath)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create output file")
@@ -331,7 +807,7 @@ func TestReadZipFileContent(t *testing.T) {
 			"test.txt": file,
 		}
 
-		_, err := createOutputZip(deduplicatedFiles, outputPath)
+		_, err := newRepackager(Options{}).createOutputZip(context.Background(), deduplicatedFiles, outputPath)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to write and hash file")
@@ -358,7 +834,7 @@ func TestReadZipFileContent(t *testing.T) {
 			deduplicatedFiles[filepath.Base(file.Name)] = file
 		}
 
-		registry, err := createOutputZip(deduplicatedFiles, outputPath)
+		registry, err := newRepackager(Options{}).createOutputZip(context.Background(), deduplicatedFiles, outputPath)
 
 		assert.NoError(t, err)
 		assert.Len(t, registry, 2)
@@ -378,6 +854,8 @@ func TestReadZipFileContent(t *testing.T) {
 	})
 }
 
+// makeTestZip writes a ZIP file with the entries in name order, so that the entry kept by
+// archive order is the same on every run.
 func makeTestZip(path string, entries map[string]string) error {
 	file, err := os.Create(path)
 	if err != nil {
@@ -388,12 +866,12 @@ func makeTestZip(path string, entries map[string]string) error {
 	zipWriter := zip.NewWriter(file)
 	defer zipWriter.Close()
 
-	for name, content := range entries {
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
 		fileWriter, err := zipWriter.Create(name)
 		if err != nil {
 			return err
 		}
-		_, err = io.Copy(fileWriter, strings.NewReader(content))
+		_, err = io.Copy(fileWriter, strings.NewReader(entries[name]))
 		if err != nil {
 			return err
 		}
@@ -435,6 +913,22 @@ func createTestZipDir(name string) *zip.File {
 	return reader.File[0]
 }
 
+// createTestZipFileWithMode builds a one-entry ZIP in memory whose entry has the given file mode.
+func createTestZipFileWithMode(name, content string, mode os.FileMode) *zip.File {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	header := &zip.FileHeader{Name: name, Method: zip.Store}
+	header.SetMode(mode)
+
+	writer, _ := zipWriter.CreateHeader(header)
+	writer.Write([]byte(content))
+	zipWriter.Close()
+
+	reader, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	return reader.File[0]
+}
+
 func createTestZipSymlink(name, target string) *zip.File {
 	// Create a temporary buffer to hold our zip file.
 	buf := new(bytes.Buffer)
@@ -460,6 +954,35 @@ func createTestZipSymlink(name, target string) *zip.File {
 	return reader.File[0]
 }
 
+// createTestZipFileWithDeclaredSize builds a one-entry ZIP in memory whose header declares
+// the given uncompressed size regardless of the actual content length.
+func createTestZipFileWithDeclaredSize(t *testing.T, name, content string, declaredSize uint64) *zip.File {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	writeTestZipEntryWithDeclaredSize(t, zipWriter, name, content, declaredSize)
+	require.NoError(t, zipWriter.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	return reader.File[0]
+}
+
+// writeTestZipEntryWithDeclaredSize writes a stored entry whose header declares the given
+// uncompressed size while keeping a correct CRC-32.
+func writeTestZipEntryWithDeclaredSize(t *testing.T, zipWriter *zip.Writer, name, content string, declaredSize uint64) {
+	writer, err := zipWriter.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(content)),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: declaredSize,
+	})
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+}
+
 func readZipFileContent(file *zip.File) (string, error) {
 	reader, err := file.Open()
 	if err != nil {
@@ -0,0 +1,129 @@
+package repackage
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// PreviousOutput is the output archive of an earlier run along with the files its report lists.
+// Its entries are copied as is instead of processing their source entries again when these are
+// unchanged.
+type PreviousOutput struct {
+	// Path of the output archive of the earlier run.
+	Path string
+
+	// Files maps each file name of the archive to its metadata in the earlier run.
+	Files map[string]FileInfo
+}
+
+// matchPreviousOutput finds the entries of the previous output whose source entry is unchanged,
+// that is an input entry at the same original path with the same size and CRC-32, and records
+// their checksum so that the source entries are never read. Files whose checksum was computed with
+// another algorithm are processed again, as are entries of the previous output whose data no
+// longer matches their CRC-32, their size or the checksum of the report.
+func (r *repackager) matchPreviousOutput(previousFiles []*zip.File, files []*zip.File) {
+	previousEntries := make(map[string]*zip.File, len(previousFiles))
+	previousHashes := make(map[string]Digest, len(previousFiles))
+	for _, previousFile := range previousFiles {
+		fileInfo, isListed := r.options.Previous.Files[previousFile.Name]
+		if !isListed || fileInfo.HashAlgorithm.OrDefault() != r.algorithm ||
+			previousFile.Method != zip.Store || int64(previousFile.UncompressedSize64) != fileInfo.Size {
+			continue
+		}
+
+		// Archives with several entries of the same name or source are not trusted.
+		if _, isTaken := previousEntries[fileInfo.OriginalPath]; isTaken {
+			previousEntries[fileInfo.OriginalPath] = nil
+			continue
+		}
+		previousEntries[fileInfo.OriginalPath] = previousFile
+		previousHashes[fileInfo.OriginalPath] = fileInfo.Hash
+	}
+
+	for _, file := range files {
+		path := r.entryPath(file)
+		previousFile := previousEntries[path]
+		if previousFile == nil || previousFile.CRC32 != file.CRC32 || previousFile.UncompressedSize64 != file.UncompressedSize64 {
+			continue
+		}
+		if err := checkPreviousEntry(previousFile, r.algorithm, previousHashes[path]); err != nil {
+			r.logger.Warn("cannot reuse a corrupted entry of the previous output, processing it again",
+				"name", previousFile.Name, "path", path, "error", err)
+			continue
+		}
+
+		r.reused[file] = previousFile
+		r.hashes.store(file, r.algorithm, measurement{size: int64(file.UncompressedSize64), hash: previousHashes[path]})
+	}
+}
+
+// checkPreviousEntry reads an entry of the previous output once, which checks its data against
+// its CRC-32 and size, and checks that it has the checksum recorded by the report, so that a
+// corrupted previous output is not carried forward.
+func checkPreviousEntry(previousFile *zip.File, algorithm HashAlgorithm, expectedHash Digest) error {
+	actualHash, err := HashOf(previousFile, algorithm)
+	if err != nil {
+		return err
+	}
+	if actualHash != expectedHash {
+		return fmt.Errorf("checksum %s differs from %s recorded by the report", actualHash, expectedHash)
+	}
+	return nil
+}
+
+// copyReusedEntry writes an entry of the output ZIP from the stored data of the previous output,
+// without reading its source entry. The entry header is the same as for a processed entry.
+func (r *repackager) copyReusedEntry(zipWriter *zip.Writer, previousFile *zip.File, name string) error {
+	rawReader, err := previousFile.OpenRaw()
+	if err != nil {
+		return err
+	}
+
+	zipFileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+
+	writtenSize, err := io.Copy(zipFileWriter, rawReader)
+	if err != nil {
+		return err
+	}
+	if writtenSize != int64(previousFile.UncompressedSize64) {
+		return fmt.Errorf("previous output entry \"%s\" is truncated", previousFile.Name)
+	}
+
+	r.countBytes(0, writtenSize)
+	return nil
+}
+
+// createOutputFile creates the file the output ZIP is written to. When a previous output is
+// reused, it may be the output itself, so a temporary file is created next to it instead and
+// renamed by commitOutputFile.
+func (r *repackager) createOutputFile(outputPath string) (*os.File, error) {
+	if r.options.Previous == nil {
+		return os.Create(outputPath)
+	}
+	return os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*.tmp")
+}
+
+// commitOutputFile closes the complete output ZIP and, when it was written to a temporary file,
+// replaces the output with it.
+func (r *repackager) commitOutputFile(zipWriter *zip.Writer, outputFile *os.File, outputPath string) error {
+	if err := zipWriter.Close(); err != nil {
+		return err
+	}
+	if err := outputFile.Close(); err != nil {
+		return err
+	}
+
+	if outputFile.Name() == outputPath {
+		return nil
+	}
+	return os.Rename(outputFile.Name(), outputPath)
+}
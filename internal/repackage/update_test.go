@@ -0,0 +1,135 @@
+package repackage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	firstEntries := map[string]string{
+		"a/same.txt":    "unchanged content",
+		"b/same.txt":    "unchanged content",
+		"docs/edit.txt": "first version",
+		"docs/gone.txt": "removed later",
+	}
+	secondEntries := map[string]string{
+		"a/same.txt":    "unchanged content",
+		"b/same.txt":    "unchanged content",
+		"docs/edit.txt": "second version!",
+		"docs/new.txt":  "added later",
+	}
+
+	// previousOutput repackages the first entries and returns the output as a previous output.
+	previousOutput := func(t *testing.T, outputPath string) *PreviousOutput {
+		inputPath := filepath.Join(tempDir, "first_input.zip")
+		require.NoError(t, makeTestZip(inputPath, firstEntries))
+
+		result, err := Run(context.Background(), []string{inputPath}, outputPath, Options{})
+		require.NoError(t, err)
+
+		return &PreviousOutput{Path: outputPath, Files: result.Files}
+	}
+
+	secondInputPath := filepath.Join(tempDir, "second_input.zip")
+	require.NoError(t, makeTestZip(secondInputPath, secondEntries))
+
+	rebuiltPath := filepath.Join(tempDir, "rebuilt_output.zip")
+	rebuiltResult, err := Run(context.Background(), []string{secondInputPath}, rebuiltPath, Options{EmbedManifest: true})
+	require.NoError(t, err)
+	rebuiltContent, err := os.ReadFile(rebuiltPath)
+	require.NoError(t, err)
+
+	t.Run("Successfully produces the same output as a full rebuild", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "updated_output.zip")
+		previous := previousOutput(t, outputPath)
+
+		hashCache := NewHashCache()
+		result, err := Run(context.Background(), []string{secondInputPath}, outputPath, Options{
+			EmbedManifest: true,
+			HashCache:     hashCache,
+			Previous:      previous,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rebuiltResult, result)
+		updatedContent, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		assert.Equal(t, rebuiltContent, updatedContent)
+
+		// Only the changed and added entries and the dropped duplicate, which is compared with
+		// the kept one, are read: the kept unchanged entry is served from the previous output.
+		assert.Equal(t, 3, hashCache.Stats().Misses)
+
+		leftovers, err := filepath.Glob(filepath.Join(tempDir, ".updated_output.zip.*"))
+		require.NoError(t, err)
+		assert.Empty(t, leftovers, "Temporary output should be renamed")
+	})
+
+	t.Run("Successfully rebuilds in full when the previous output cannot be read", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "unreadable_output.zip")
+		previous := previousOutput(t, outputPath)
+		require.NoError(t, os.WriteFile(outputPath, []byte("not a zip"), 0o644))
+
+		result, err := Run(context.Background(), []string{secondInputPath}, outputPath, Options{
+			EmbedManifest: true,
+			Previous:      previous,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rebuiltResult, result)
+		updatedContent, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		assert.Equal(t, rebuiltContent, updatedContent)
+	})
+
+	t.Run("Successfully processes again entries corrupted in the previous output", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "corrupted_output.zip")
+		previous := previousOutput(t, outputPath)
+		content, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		corrupted := bytes.ReplaceAll(content, []byte("unchanged content"), []byte("unchanged CONTENT"))
+		require.NoError(t, os.WriteFile(outputPath, corrupted, 0o644))
+
+		hashCache := NewHashCache()
+		result, err := Run(context.Background(), []string{secondInputPath}, outputPath, Options{
+			EmbedManifest: true,
+			HashCache:     hashCache,
+			Previous:      previous,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rebuiltResult, result)
+		updatedContent, err := os.ReadFile(outputPath)
+		require.NoError(t, err)
+		assert.Equal(t, rebuiltContent, updatedContent)
+		assert.Equal(t, 4, hashCache.Stats().Misses)
+	})
+
+	t.Run("Successfully ignores previous files whose source path changed", func(t *testing.T) {
+		outputPath := filepath.Join(tempDir, "moved_output.zip")
+		previous := previousOutput(t, outputPath)
+		for name, fileInfo := range previous.Files {
+			fileInfo.OriginalPath = "moved/" + fileInfo.OriginalPath
+			previous.Files[name] = fileInfo
+		}
+
+		hashCache := NewHashCache()
+		result, err := Run(context.Background(), []string{secondInputPath}, outputPath, Options{
+			EmbedManifest: true,
+			HashCache:     hashCache,
+			Previous:      previous,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, rebuiltResult, result)
+		assert.Equal(t, 4, hashCache.Stats().Misses)
+	})
+}
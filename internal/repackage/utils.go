@@ -2,8 +2,11 @@ package repackage
 
 import (
 	"archive/zip"
-	"crypto/sha256"
+	"compress/flate"
 	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -22,16 +25,185 @@ const (
 	ioReparseMount = 0xA0000003
 )
 
-func areFileHashesIdentical(file1, file2 *zip.File) (bool, error) {
-	hash1, err := HashOf(file1)
+// areFileHashesIdentical reports whether two entries have the same checksum. When the algorithm
+// is not collision resistant, matching checksums are confirmed by comparing SHA-256 checksums.
+func (r *repackager) areFileHashesIdentical(file1, file2 *zip.File) (bool, error) {
+	hash1, err := r.hashOf(file1)
 	if err != nil {
 		return false, err
 	}
-	hash2, err := HashOf(file2)
+	hash2, err := r.hashOf(file2)
 	if err != nil {
 		return false, err
 	}
-	return hash1 == hash2, nil
+	if hash1 != hash2 || r.algorithm.IsCollisionResistant() {
+		return hash1 == hash2, nil
+	}
+
+	sha1, err := r.sha256Of(file1)
+	if err != nil {
+		return false, err
+	}
+	sha2, err := r.sha256Of(file2)
+	if err != nil {
+		return false, err
+	}
+	return sha1 == sha2, nil
+}
+
+// groupByContent splits entries sharing a checksum into groups of entries with identical
+// content, keeping their order. When the algorithm is not collision resistant, the entries are
+// grouped by SHA-256 checksum, so that entries whose checksums only collide are kept apart.
+func (r *repackager) groupByContent(sameHashFiles []*zip.File) ([][]*zip.File, error) {
+	if r.algorithm.IsCollisionResistant() {
+		return [][]*zip.File{sameHashFiles}, nil
+	}
+
+	var groups [][]*zip.File
+	groupIndexes := make(map[Digest]int, len(sameHashFiles))
+	for _, file := range sameHashFiles {
+		sha, err := r.sha256Of(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed hashing file \"%s\": %w", r.entryPath(file), err)
+		}
+
+		index, exists := groupIndexes[sha]
+		if !exists {
+			index = len(groups)
+			groupIndexes[sha] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], file)
+	}
+
+	return groups, nil
+}
+
+// sizeOf returns the uncompressed size of an entry, either as declared in its header or,
+// in VerifySizes mode, as measured by decompressing it.
+func (r *repackager) sizeOf(file *zip.File) (int64, error) {
+	if !r.options.VerifySizes {
+		return file.FileInfo().Size(), nil
+	}
+
+	entryMeasurement, err := r.measure(file)
+	if err != nil {
+		return 0, err
+	}
+	return entryMeasurement.size, nil
+}
+
+// hashOf returns the checksum of an entry, reading it at most once per run.
+func (r *repackager) hashOf(file *zip.File) (Digest, error) {
+	entryMeasurement, err := r.measure(file)
+	if err != nil {
+		return "", err
+	}
+	return entryMeasurement.hash, nil
+}
+
+// sha256Of returns the SHA-256 checksum of an entry, used by rename placeholders so that names do
+// not depend on the hash algorithm, and to confirm checksums of algorithms that are not collision
+// resistant. It is only computed again when the algorithm is not SHA-256.
+func (r *repackager) sha256Of(file *zip.File) (Digest, error) {
+	entryMeasurement, err := r.measureWith(file, HashSHA256)
+	if err != nil {
+		return "", err
+	}
+	return entryMeasurement.hash, nil
+}
+
+// measure reads an entry, unless it is cached, to compute its actual size and hash, and records
+// a size mismatch when the size declared in the header is different.
+func (r *repackager) measure(file *zip.File) (measurement, error) {
+	return r.measureWith(file, r.algorithm)
+}
+
+// measureWith measures an entry like measure, with the given hash algorithm.
+func (r *repackager) measureWith(file *zip.File, algorithm HashAlgorithm) (measurement, error) {
+	entryMeasurement, isFresh, err := r.hashes.measure(file, algorithm, r.openCountedEntry)
+	if err != nil {
+		return measurement{}, err
+	}
+
+	// Mismatches are recorded once, when measuring with the hash algorithm of the run.
+	if declaredSize := file.FileInfo().Size(); isFresh && algorithm == r.algorithm && declaredSize != entryMeasurement.size {
+		r.sizeMismatches = append(r.sizeMismatches, SizeMismatch{
+			Path:         r.entryPath(file),
+			DeclaredSize: declaredSize,
+			ActualSize:   entryMeasurement.size,
+		})
+	}
+
+	return entryMeasurement, nil
+}
+
+// openEntry opens an entry for copying into the output ZIP. In VerifySizes mode the declared
+// size is not enforced, so that entries with a wrong header can still be copied in full.
+func (r *repackager) openEntry(file *zip.File) (io.ReadCloser, error) {
+	if r.options.VerifySizes {
+		return openMeasured(file)
+	}
+	return file.Open()
+}
+
+// openCountedEntry opens an entry like openEntry, counting the bytes read in the progress.
+func (r *repackager) openCountedEntry(file *zip.File) (io.ReadCloser, error) {
+	reader, err := r.openEntry(file)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReader{reader: reader, count: func(n int64) { r.countBytes(n, 0) }}, nil
+}
+
+// openMeasured opens an entry without relying on its declared uncompressed size, which
+// archive/zip enforces while reading. Stored and deflated entries are decompressed from
+// their raw data and checked against the CRC-32 from the header; entries using any other
+// method fall back to the standard reader.
+func openMeasured(file *zip.File) (io.ReadCloser, error) {
+	var decompressor io.ReadCloser
+	switch file.Method {
+	case zip.Store:
+		rawReader, err := file.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		decompressor = io.NopCloser(rawReader)
+	case zip.Deflate:
+		rawReader, err := file.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		decompressor = flate.NewReader(rawReader)
+	default:
+		return file.Open()
+	}
+
+	return &crcCheckingReader{
+		reader:      decompressor,
+		crc:         crc32.NewIEEE(),
+		expectedCRC: file.CRC32,
+	}, nil
+}
+
+// crcCheckingReader verifies the CRC-32 of the data read from an entry once it is exhausted.
+type crcCheckingReader struct {
+	reader      io.ReadCloser
+	crc         hash.Hash32
+	expectedCRC uint32
+}
+
+func (c *crcCheckingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.crc.Write(p[:n])
+	if err == io.EOF && c.expectedCRC != 0 && c.crc.Sum32() != c.expectedCRC {
+		return n, zip.ErrChecksum
+	}
+	return n, err
+}
+
+func (c *crcCheckingReader) Close() error {
+	return c.reader.Close()
 }
 
 // isSymlink detects if a ZIP entry is a symbolic link, which should be excluded during flattening.
@@ -111,31 +283,43 @@ func isMetadataFile(fileName string) bool {
 	return false
 }
 
-func HashOf(file *zip.File) ([32]byte, error) {
+// HashOf returns the checksum of an entry computed with the algorithm.
+func HashOf(file *zip.File, algorithm HashAlgorithm) (Digest, error) {
 	reader, err := file.Open()
 	if err != nil {
-		return [32]byte{}, err
+		return "", err
 	}
 	defer reader.Close()
 
-	// Creates a SHA-256 hash calculator.
-	hashCalculator := sha256.New()
+	hashCalculator := algorithm.New()
 	if _, err := io.Copy(hashCalculator, reader); err != nil {
-		return [32]byte{}, err
+		return "", err
 	}
 
-	var hash [32]byte
-	copy(hash[:], hashCalculator.Sum(nil))
-	return hash, nil
+	return Digest(hashCalculator.Sum(nil)), nil
 }
 
-// writeAndHashEntry writes a ZIP entry uncompressed and computes its SHA-256.
-func writeAndHashEntry(zipWriter *zip.Writer, file *zip.File, name string) ([32]byte, error) {
-	fileReader, err := file.Open()
+// writeAndHashEntry writes a ZIP entry uncompressed and computes its size and checksum,
+// unless they were already computed during deduplication. Entries unchanged since the previous
+// output are copied from it instead.
+func (r *repackager) writeAndHashEntry(zipWriter *zip.Writer, file *zip.File, name string) (measurement, error) {
+	if previousFile, isReused := r.reused[file]; isReused {
+		if err := r.copyReusedEntry(zipWriter, previousFile, name); err != nil {
+			return measurement{}, err
+		}
+		r.logger.Debug("reused entry", "name", name, "path", r.entryPath(file))
+		cached, _ := r.hashes.lookup(file, r.algorithm)
+		return cached, nil
+	}
+
+	entryReader, err := r.openEntry(file)
 	if err != nil {
-		return [32]byte{}, err
+		return measurement{}, err
 	}
-	defer fileReader.Close()
+	defer entryReader.Close()
+
+	// Entries are stored uncompressed, so every byte read is written as is.
+	fileReader := &countingReader{reader: entryReader, count: func(n int64) { r.countBytes(n, n) }}
 
 	// Prepare header for store mode (no compression).
 	header := &zip.FileHeader{
@@ -145,18 +329,26 @@ func writeAndHashEntry(zipWriter *zip.Writer, file *zip.File, name string) ([32]
 
 	zipFileWriter, err := zipWriter.CreateHeader(header)
 	if err != nil {
-		return [32]byte{}, err
+		return measurement{}, err
+	}
+
+	if cached, ok := r.hashes.lookup(file, r.algorithm); ok {
+		if _, err := io.Copy(zipFileWriter, fileReader); err != nil {
+			return measurement{}, err
+		}
+		return cached, nil
 	}
 
 	// Set up multi-writer for zip entry and hasher.
-	hashCalculator := sha256.New()
+	hashCalculator := r.algorithm.New()
 	multiWriter := io.MultiWriter(zipFileWriter, hashCalculator)
 
-	if _, err := io.Copy(multiWriter, fileReader); err != nil {
-		return [32]byte{}, err
+	writtenSize, err := io.Copy(multiWriter, fileReader)
+	if err != nil {
+		return measurement{}, err
 	}
 
-	var hash [32]byte
-	copy(hash[:], hashCalculator.Sum(nil))
-	return hash, nil
+	entryMeasurement := measurement{size: writtenSize, hash: Digest(hashCalculator.Sum(nil))}
+	r.hashes.store(file, r.algorithm, entryMeasurement)
+	return entryMeasurement, nil
 }
@@ -0,0 +1,413 @@
+package sums
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+// Status is the outcome of the check of a file listed in a checksum file.
+type Status string
+
+const (
+	// StatusOK marks a file whose checksum matches the listed one.
+	StatusOK Status = "ok"
+
+	// StatusFailed marks a file whose checksum differs from the listed one.
+	StatusFailed Status = "failed"
+
+	// StatusMissing marks a listed file that is neither an entry of the archive nor the archive itself.
+	StatusMissing Status = "missing"
+)
+
+// Line is a line of a checksum file: the hexadecimal checksum of a file and its name.
+type Line struct {
+	Checksum string
+	Name     string
+}
+
+// Check is the outcome of the check of a line of a checksum file.
+type Check struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Expected string `json:"expected"`
+
+	// Actual is the checksum of the file. It is empty for missing files.
+	Actual string `json:"actual,omitempty"`
+}
+
+// Summary counts the checked files by status.
+type Summary struct {
+	OK      int `json:"ok"`
+	Failed  int `json:"failed"`
+	Missing int `json:"missing"`
+
+	// Unlisted is the number of files of the archive that the checksum file does not list. They
+	// do not fail the verification, as with sha256sum -c.
+	Unlisted int `json:"unlisted"`
+}
+
+// Result is the outcome of the verification of an archive against a checksum file.
+type Result struct {
+	// Valid reports whether every listed file was found with the listed checksum.
+	Valid bool `json:"valid"`
+
+	// HashAlgorithm is the algorithm of the listed checksums, given or inferred from them.
+	HashAlgorithm repackage.HashAlgorithm `json:"hash_algorithm"`
+
+	Summary Summary `json:"summary"`
+
+	// Checks lists the outcome of every line of the checksum file, in file order.
+	Checks []Check `json:"checks"`
+
+	// Unlisted lists the files of the archive that the checksum file does not list, sorted by name.
+	Unlisted []string `json:"unlisted"`
+}
+
+// InputError reports that the archive or the checksum file could not be read.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// OutputError reports that the checksum file could not be written.
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string { return e.Err.Error() }
+func (e *OutputError) Unwrap() error { return e.Err }
+
+// Write writes a checksum file in the format of sha256sum listing every file of a repackaging
+// result, sorted by name, followed by the output archive itself. The checksums are those
+// computed while repackaging with the algorithm of the result, and the archive is named by its
+// path relative to the checksum file, so that running sha256sum -c (or sha512sum or b2sum for
+// the matching algorithm) next to the checksum file checks the archive and its extracted files.
+// The embedded manifest is not listed. Failures are reported as an *OutputError.
+func Write(sumsPath, outputZipPath string, result *repackage.Result, algorithm repackage.HashAlgorithm) error {
+	lines := make([]Line, 0, len(result.Files)+1)
+	for _, name := range slices.Sorted(maps.Keys(result.Files)) {
+		lines = append(lines, Line{Checksum: result.Files[name].Hash.String(), Name: name})
+	}
+
+	archiveChecksum, err := hashFile(outputZipPath, algorithm)
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("failed to hash output zip %s: %w", outputZipPath, err)}
+	}
+	lines = append(lines, Line{Checksum: archiveChecksum.String(), Name: archiveName(sumsPath, outputZipPath)})
+
+	sumsFile, err := os.Create(sumsPath)
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("failed to create checksum file: %w", err)}
+	}
+	defer sumsFile.Close()
+
+	writer := bufio.NewWriter(sumsFile)
+	for _, line := range lines {
+		writer.WriteString(formatLine(line))
+	}
+	if err := writer.Flush(); err != nil {
+		return &OutputError{Err: fmt.Errorf("failed to write checksum file: %w", err)}
+	}
+
+	return nil
+}
+
+// Verify checks an archive against a checksum file in the format of sha256sum. Every listed
+// name is looked up among the entries of the archive and hashed with the algorithm; a name that
+// is not an entry but refers, relative to the checksum file, to the archive itself checks the
+// archive. When the algorithm is empty, it is inferred from the length of the checksums, and
+// 128-digit checksums are taken as BLAKE2b-512 when any listed file matches its BLAKE2b-512
+// checksum, and as SHA-512 otherwise. Failures to read the archive or the checksum
+// file are reported as an *InputError, and cancelling the context stops the verification
+// between files with the context error.
+func Verify(ctx context.Context, zipPath, sumsPath string, algorithm repackage.HashAlgorithm) (*Result, error) {
+	isAlgorithmGiven := algorithm != ""
+	lines, algorithm, err := readLines(sumsPath, algorithm)
+	if err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, &InputError{Err: fmt.Errorf("failed to open zip %s: %w", zipPath, err)}
+	}
+	defer reader.Close()
+
+	entries := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() {
+			entries[file.Name] = file
+		}
+	}
+
+	if algorithm == repackage.HashSHA512 && !isAlgorithmGiven {
+		algorithm, err = resolveSHA512(lines, entries, zipPath, sumsPath)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+	}
+
+	result := &Result{HashAlgorithm: algorithm, Checks: make([]Check, 0, len(lines)), Unlisted: []string{}}
+	listed := make(map[string]bool, len(lines))
+
+	for _, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		check := Check{Name: line.Name, Expected: line.Checksum}
+		listed[line.Name] = true
+
+		actual, isFound, err := hashListed(line.Name, entries, zipPath, sumsPath, algorithm)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		if !isFound {
+			check.Status = StatusMissing
+			result.Checks = append(result.Checks, check)
+			result.Summary.Missing++
+			continue
+		}
+
+		check.Actual = actual.String()
+		if check.Actual == line.Checksum {
+			check.Status = StatusOK
+			result.Summary.OK++
+		} else {
+			check.Status = StatusFailed
+			result.Summary.Failed++
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	for name := range entries {
+		if !listed[name] {
+			result.Unlisted = append(result.Unlisted, name)
+		}
+	}
+	slices.Sort(result.Unlisted)
+	result.Summary.Unlisted = len(result.Unlisted)
+
+	result.Valid = result.Summary.Failed == 0 && result.Summary.Missing == 0
+	return result, nil
+}
+
+// readLines reads and parses a checksum file, checking that its checksums have the length of
+// those of the algorithm. When the algorithm is empty, it is inferred from the length of the
+// first checksum, taking 128-digit checksums as SHA-512, and returned.
+func readLines(sumsPath string, algorithm repackage.HashAlgorithm) ([]Line, repackage.HashAlgorithm, error) {
+	sumsFile, err := os.Open(sumsPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open checksum file: %w", err)
+	}
+	defer sumsFile.Close()
+
+	var lines []Line
+	scanner := bufio.NewScanner(sumsFile)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		line, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid line %d of checksum file %s: %w", lineNumber, sumsPath, err)
+		}
+		if algorithm == "" {
+			algorithm, err = algorithmOf(line.Checksum)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid line %d of checksum file %s: %w", lineNumber, sumsPath, err)
+			}
+		}
+		digest, err := algorithm.ParseDigest(line.Checksum)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid line %d of checksum file %s: %w", lineNumber, sumsPath, err)
+		}
+		// Compare checksums in their canonical lowercase form.
+		line.Checksum = digest.String()
+
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	return lines, algorithm.OrDefault(), nil
+}
+
+// algorithmOf returns the algorithm whose checksums have the length of a hexadecimal checksum.
+// SHA-512 is returned for 128-digit checksums, which may also be BLAKE2b-512 checksums.
+func algorithmOf(checksum string) (repackage.HashAlgorithm, error) {
+	for _, algorithm := range []repackage.HashAlgorithm{repackage.HashSHA256, repackage.HashSHA512, repackage.HashCRC32} {
+		if len(checksum) == 2*algorithm.Size() {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf("checksum %q has %d hexadecimal digits, which matches no hash algorithm", checksum, len(checksum))
+}
+
+// resolveSHA512 tells BLAKE2b-512 checksums apart from SHA-512 checksums, which have the same
+// length, by hashing the listed files with BLAKE2b-512 until one matches its checksum. It returns
+// HashBLAKE2b when one does, so that corrupted files are reported as such, and HashSHA512
+// otherwise, including when no listed file is found.
+func resolveSHA512(lines []Line, entries map[string]*zip.File, zipPath, sumsPath string) (repackage.HashAlgorithm, error) {
+	for _, line := range lines {
+		actual, isFound, err := hashListed(line.Name, entries, zipPath, sumsPath, repackage.HashBLAKE2b)
+		if err != nil {
+			return "", err
+		}
+		if isFound && actual.String() == line.Checksum {
+			return repackage.HashBLAKE2b, nil
+		}
+	}
+	return repackage.HashSHA512, nil
+}
+
+// hashListed returns the checksum of a file listed in a checksum file, either an entry of the
+// archive or the archive itself, and whether the file was found.
+func hashListed(name string, entries map[string]*zip.File, zipPath, sumsPath string,
+	algorithm repackage.HashAlgorithm) (repackage.Digest, bool, error) {
+	if file, exists := entries[name]; exists {
+		actual, err := repackage.HashOf(file, algorithm)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to hash entry %s: %w", name, err)
+		}
+		return actual, true, nil
+	}
+
+	if isArchive(sumsPath, name, zipPath) {
+		actual, err := hashFile(zipPath, algorithm)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to hash zip %s: %w", zipPath, err)
+		}
+		return actual, true, nil
+	}
+
+	return "", false, nil
+}
+
+// formatLine formats a line of a checksum file in text mode. As with sha256sum, names
+// containing a backslash or a line break are escaped and the line then starts with a backslash.
+func formatLine(line Line) string {
+	if !strings.ContainsAny(line.Name, "\\\n\r") {
+		return line.Checksum + "  " + line.Name + "\n"
+	}
+	return "\\" + line.Checksum + "  " + nameEscaper.Replace(line.Name) + "\n"
+}
+
+// parseLine parses a line of a checksum file written in text mode ("  ") or binary mode (" *").
+func parseLine(text string) (Line, error) {
+	escaped := strings.HasPrefix(text, "\\")
+	if escaped {
+		text = text[1:]
+	}
+
+	checksum, name, found := strings.Cut(text, " ")
+	if !found || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+		return Line{}, fmt.Errorf("expected a checksum, two spaces and a name")
+	}
+	name = name[1:]
+
+	if escaped {
+		unescaped, err := unescapeName(name)
+		if err != nil {
+			return Line{}, err
+		}
+		name = unescaped
+	}
+
+	return Line{Checksum: checksum, Name: name}, nil
+}
+
+// nameEscaper escapes the characters of names that cannot appear verbatim on a line.
+var nameEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// unescapeName reverses nameEscaper.
+func unescapeName(name string) (string, error) {
+	var builder strings.Builder
+	for index := 0; index < len(name); index++ {
+		if name[index] != '\\' {
+			builder.WriteByte(name[index])
+			continue
+		}
+
+		index++
+		if index == len(name) {
+			return "", fmt.Errorf("name %q ends with a backslash", name)
+		}
+		switch name[index] {
+		case '\\':
+			builder.WriteByte('\\')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("name %q contains an unknown escape sequence", name)
+		}
+	}
+	return builder.String(), nil
+}
+
+// archiveName returns the name of the archive in a checksum file: its path relative to the
+// directory of the checksum file, or its absolute path when there is none.
+func archiveName(sumsPath, zipPath string) string {
+	sumsDir, err := filepath.Abs(filepath.Dir(sumsPath))
+	if err != nil {
+		return zipPath
+	}
+	absoluteZipPath, err := filepath.Abs(zipPath)
+	if err != nil {
+		return zipPath
+	}
+	if relativePath, err := filepath.Rel(sumsDir, absoluteZipPath); err == nil {
+		return relativePath
+	}
+	return absoluteZipPath
+}
+
+// isArchive reports whether a name listed in a checksum file refers to the archive, resolving
+// relative names against the directory of the checksum file.
+func isArchive(sumsPath, name, zipPath string) bool {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(sumsPath), name)
+	}
+
+	listedInfo, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	zipInfo, err := os.Stat(zipPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(listedInfo, zipInfo)
+}
+
+// hashFile returns the checksum of a file computed with the algorithm.
+func hashFile(path string, algorithm repackage.HashAlgorithm) (repackage.Digest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hashCalculator := algorithm.New()
+	if _, err := io.Copy(hashCalculator, file); err != nil {
+		return "", err
+	}
+
+	return repackage.Digest(hashCalculator.Sum(nil)), nil
+}
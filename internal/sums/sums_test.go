@@ -0,0 +1,249 @@
+package sums
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/ziptest"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestWrite(t *testing.T) {
+	tempDir := t.TempDir()
+
+	inputZipPath := filepath.Join(tempDir, "input.zip")
+	require.NoError(t, ziptest.Write(inputZipPath, [][2]string{
+		{"lib/b.txt", "second"},
+		{"docs/a.txt", "first"},
+		{"docs/line\nbreak", "escaped"},
+	}))
+
+	outputZipPath := filepath.Join(tempDir, "dist", "output.zip")
+	require.NoError(t, os.Mkdir(filepath.Dir(outputZipPath), 0o755))
+	result, err := repackage.Run(context.Background(), []string{inputZipPath}, outputZipPath, repackage.Options{})
+	require.NoError(t, err)
+
+	t.Run("Returns error when the checksum file cannot be created", func(t *testing.T) {
+		err := Write(filepath.Join(tempDir, "missing", "SHA256SUMS"), outputZipPath, result, repackage.HashSHA256)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create checksum file")
+		var typedErr *OutputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Successfully lists the files and the archive", func(t *testing.T) {
+		sumsPath := filepath.Join(tempDir, "SHA256SUMS")
+
+		err := Write(sumsPath, outputZipPath, result, repackage.HashSHA256)
+
+		assert.NoError(t, err)
+		archive, err := os.ReadFile(outputZipPath)
+		require.NoError(t, err)
+		content, err := os.ReadFile(sumsPath)
+		require.NoError(t, err)
+		assert.Equal(t,
+			sha256Hex("first")+"  a.txt\n"+
+				sha256Hex("second")+"  b.txt\n"+
+				"\\"+sha256Hex("escaped")+"  line\\nbreak\n"+
+				sha256Hex(string(archive))+"  dist/output.zip\n",
+			string(content))
+	})
+
+	t.Run("Successfully writes checksums verified without giving their algorithm", func(t *testing.T) {
+		for _, algorithm := range []repackage.HashAlgorithm{repackage.HashSHA512, repackage.HashBLAKE2b, repackage.HashCRC32} {
+			algorithmZipPath := filepath.Join(tempDir, "dist", string(algorithm)+".zip")
+			algorithmResult, err := repackage.Run(context.Background(), []string{inputZipPath}, algorithmZipPath,
+				repackage.Options{HashAlgorithm: algorithm})
+			require.NoError(t, err)
+			sumsPath := filepath.Join(tempDir, string(algorithm)+"SUMS")
+			require.NoError(t, Write(sumsPath, algorithmZipPath, algorithmResult, algorithm))
+
+			verifyResult, err := Verify(context.Background(), algorithmZipPath, sumsPath, "")
+
+			assert.NoError(t, err)
+			assert.True(t, verifyResult.Valid, "Checksums of %s should verify", algorithm)
+			assert.Equal(t, algorithm, verifyResult.HashAlgorithm)
+			assert.Equal(t, Summary{OK: 4}, verifyResult.Summary)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	tempDir := t.TempDir()
+
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	require.NoError(t, ziptest.Write(zipPath, [][2]string{
+		{"a.txt", "first"},
+		{"b.txt", "second"},
+		{"extra.txt", "not listed"},
+		{"docs/", ""},
+	}))
+	archive, err := os.ReadFile(zipPath)
+	require.NoError(t, err)
+
+	writeSums := func(t *testing.T, content string) string {
+		sumsPath := filepath.Join(tempDir, "SHA256SUMS")
+		require.NoError(t, os.WriteFile(sumsPath, []byte(content), 0o644))
+		return sumsPath
+	}
+
+	t.Run("Returns error when the checksum file cannot be read", func(t *testing.T) {
+		result, err := Verify(context.Background(), zipPath, filepath.Join(tempDir, "missing"), repackage.HashSHA256)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to open checksum file")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when a line is malformed", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n"+sha256Hex("second")+"\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, repackage.HashSHA256)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid line 2 of checksum file")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when checksums were computed with another algorithm", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, repackage.HashSHA512)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid sha512 checksum")
+	})
+
+	t.Run("Returns error when a checksum matches no algorithm", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")[:40]+"  a.txt\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, "")
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "has 40 hexadecimal digits, which matches no hash algorithm")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when the archive cannot be opened", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n")
+
+		result, err := Verify(context.Background(), filepath.Join(tempDir, "missing.zip"), sumsPath, repackage.HashSHA256)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to open zip")
+		var typedErr *InputError
+		assert.ErrorAs(t, err, &typedErr)
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := Verify(ctx, zipPath, sumsPath, repackage.HashSHA256)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, result)
+	})
+
+	t.Run("Successfully reports failed, missing and unlisted files", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n"+
+			sha256Hex("changed")+" *b.txt\n"+
+			sha256Hex("gone")+"  gone.txt\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, repackage.HashSHA256)
+
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, Summary{OK: 1, Failed: 1, Missing: 1, Unlisted: 1}, result.Summary)
+		assert.Equal(t, []Check{
+			{Name: "a.txt", Status: StatusOK, Expected: sha256Hex("first"), Actual: sha256Hex("first")},
+			{Name: "b.txt", Status: StatusFailed, Expected: sha256Hex("changed"), Actual: sha256Hex("second")},
+			{Name: "gone.txt", Status: StatusMissing, Expected: sha256Hex("gone")},
+		}, result.Checks)
+		assert.Equal(t, []string{"extra.txt"}, result.Unlisted)
+	})
+
+	t.Run("Successfully infers BLAKE2b-512 checksums when the first listed file is corrupted", func(t *testing.T) {
+		sumsPath := writeSums(t, blake2bHex("changed")+"  a.txt\n"+blake2bHex("second")+"  b.txt\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, "")
+
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, repackage.HashBLAKE2b, result.HashAlgorithm)
+		assert.Equal(t, Summary{OK: 1, Failed: 1, Unlisted: 1}, result.Summary)
+		assert.Equal(t, []Check{
+			{Name: "a.txt", Status: StatusFailed, Expected: blake2bHex("changed"), Actual: blake2bHex("first")},
+			{Name: "b.txt", Status: StatusOK, Expected: blake2bHex("second"), Actual: blake2bHex("second")},
+		}, result.Checks)
+	})
+
+	t.Run("Successfully checks the archive itself", func(t *testing.T) {
+		sumsPath := writeSums(t, sha256Hex("first")+"  a.txt\n\n"+sha256Hex(string(archive))+"  archive.zip\n")
+
+		result, err := Verify(context.Background(), zipPath, sumsPath, repackage.HashSHA256)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, repackage.HashSHA256, result.HashAlgorithm)
+		assert.Equal(t, Summary{OK: 2, Unlisted: 2}, result.Summary)
+		assert.Equal(t, []string{"b.txt", "extra.txt"}, result.Unlisted)
+	})
+}
+
+func TestParseLine(t *testing.T) {
+	t.Run("Returns error without separator", func(t *testing.T) {
+		_, err := parseLine(sha256Hex("first") + " a.txt")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "expected a checksum, two spaces and a name")
+	})
+
+	t.Run("Returns error with an unknown escape sequence", func(t *testing.T) {
+		_, err := parseLine("\\" + sha256Hex("first") + "  a\\t.txt")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown escape sequence")
+	})
+
+	t.Run("Successfully parses the lines it formats", func(t *testing.T) {
+		for _, name := range []string{"a.txt", "with space.txt", "back\\slash", "line\nbreak", "*star"} {
+			line := Line{Checksum: sha256Hex(name), Name: name}
+			formatted := formatLine(line)
+
+			parsed, err := parseLine(formatted[:len(formatted)-1])
+
+			assert.NoError(t, err)
+			assert.Equal(t, line, parsed)
+		}
+	})
+}
+
+// sha256Hex returns the hexadecimal SHA-256 checksum of content.
+func sha256Hex(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// blake2bHex returns the hexadecimal BLAKE2b-512 checksum of content.
+func blake2bHex(content string) string {
+	hash := blake2b.Sum512([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,399 @@
+package validate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReportFormat is the format of the validation report.
+type ReportFormat string
+
+const (
+	// ReportJSON writes the report as a single indented JSON document described by ReportSchema.
+	ReportJSON ReportFormat = "json"
+
+	// ReportNDJSON writes the report as newline-delimited JSON, starting with a "report" record
+	// holding the metadata and summary, followed by one record per file, skipped entry, conflict,
+	// size mismatch, output problem and input problem.
+	ReportNDJSON ReportFormat = "ndjson"
+
+	// ReportCSV writes the output files as a table with one row per file.
+	ReportCSV ReportFormat = "csv"
+
+	// ReportJUnit writes the report as JUnit XML, with a test case per output file that fails on
+	// checksum mismatches, a skipped test case per skipped entry and a failed test case per output
+	// or input problem.
+	ReportJUnit ReportFormat = "junit"
+
+	// ReportMarkdown writes the report as Markdown tables, e.g. for CI job summaries.
+	ReportMarkdown ReportFormat = "markdown"
+)
+
+// StdoutReportPath is the report path that writes the report to the standard output.
+const StdoutReportPath = "-"
+
+// reportListSeparator separates the items of list columns in a CSV report.
+const reportListSeparator = ";"
+
+// Record types of an NDJSON report.
+const (
+	ndjsonReportRecord        = "report"
+	ndjsonFileRecord          = "file"
+	ndjsonSkippedRecord       = "skipped"
+	ndjsonConflictRecord      = "conflict"
+	ndjsonSizeMismatchRecord  = "size_mismatch"
+	ndjsonOutputProblemRecord = "output_problem"
+	ndjsonInputProblemRecord  = "input_problem"
+)
+
+// junitTestSuites is the root element of a JUnit report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of a JUnit report.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase represents an output file or a skipped entry in a JUnit report.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+// junitMessage is the failure or skip message of a JUnit test case.
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseReportFormat converts a command-line value into a ReportFormat.
+func ParseReportFormat(value string) (ReportFormat, error) {
+	switch format := ReportFormat(value); format {
+	case ReportJSON, ReportNDJSON, ReportCSV, ReportJUnit, ReportMarkdown:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q: expected %s, %s, %s, %s or %s",
+			value, ReportJSON, ReportNDJSON, ReportCSV, ReportJUnit, ReportMarkdown)
+	}
+}
+
+// extension returns the file extension of reports in the format, including its dot.
+func (f ReportFormat) extension() string {
+	switch f {
+	case ReportNDJSON:
+		return ".ndjson"
+	case ReportCSV:
+		return ".csv"
+	case ReportJUnit:
+		return ".xml"
+	case ReportMarkdown:
+		return ".md"
+	default:
+		return ".json"
+	}
+}
+
+// encodeReport writes the report in the given format. The empty format is ReportJSON.
+func encodeReport(writer io.Writer, report validationReport, format ReportFormat) error {
+	switch format {
+	case ReportNDJSON:
+		return encodeNDJSONReport(writer, report)
+	case ReportCSV:
+		return encodeCSVReport(writer, report)
+	case ReportJUnit:
+		return encodeJUnitReport(writer, report)
+	case ReportMarkdown:
+		return encodeMarkdownReport(writer, report)
+	default:
+		return encodeJSONReport(writer, report)
+	}
+}
+
+func encodeJSONReport(writer io.Writer, report validationReport) error {
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error generating JSON report: %w", err)
+	}
+
+	_, err = writer.Write(jsonData)
+	return err
+}
+
+func encodeNDJSONReport(writer io.Writer, report validationReport) error {
+	records := []any{struct {
+		Record   string         `json:"record"`
+		Version  int            `json:"version"`
+		Metadata reportMetadata `json:"metadata"`
+		Summary  reportSummary  `json:"summary"`
+	}{ndjsonReportRecord, report.Version, report.Metadata, report.Summary}}
+
+	for _, file := range report.Files {
+		records = append(records, struct {
+			Record string `json:"record"`
+			validationResult
+		}{ndjsonFileRecord, file})
+	}
+	for _, skipped := range report.Skipped {
+		records = append(records, struct {
+			Record string `json:"record"`
+			skippedResult
+		}{ndjsonSkippedRecord, skipped})
+	}
+	for _, conflict := range report.Conflicts {
+		records = append(records, struct {
+			Record string `json:"record"`
+			conflictResult
+		}{ndjsonConflictRecord, conflict})
+	}
+	for _, mismatch := range report.SizeMismatches {
+		records = append(records, struct {
+			Record string `json:"record"`
+			sizeMismatchResult
+		}{ndjsonSizeMismatchRecord, mismatch})
+	}
+	for _, problem := range report.OutputProblems {
+		records = append(records, struct {
+			Record string `json:"record"`
+			outputProblem
+		}{ndjsonOutputProblemRecord, problem})
+	}
+	for _, problem := range report.InputProblems {
+		records = append(records, struct {
+			Record string `json:"record"`
+			inputProblem
+		}{ndjsonInputProblemRecord, problem})
+	}
+
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("error generating NDJSON report: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func encodeCSVReport(writer io.Writer, report validationReport) error {
+	csvWriter := csv.NewWriter(writer)
+
+	rows := [][]string{{
+		"file_name", "original_path", "original_sha", "new_sha", "original_size", "new_size", "match",
+		"case_collisions", "aliases", "source_sha",
+	}}
+	for _, file := range report.Files {
+		aliases := make([]string, 0, len(file.Aliases))
+		for _, alias := range file.Aliases {
+			aliases = append(aliases, alias.FileName+"="+alias.OriginalPath)
+		}
+
+		rows = append(rows, []string{
+			file.FileName,
+			file.OriginalPath,
+			file.OriginalSHA,
+			file.NewSHA,
+			strconv.FormatInt(file.OriginalSize, 10),
+			strconv.FormatInt(file.NewSize, 10),
+			strconv.FormatBool(file.Match),
+			strings.Join(file.CaseCollisions, reportListSeparator),
+			strings.Join(aliases, reportListSeparator),
+			file.SourceSHA,
+		})
+	}
+
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return fmt.Errorf("error generating CSV report: %w", err)
+	}
+
+	return nil
+}
+
+func encodeJUnitReport(writer io.Writer, report validationReport) error {
+	duration := strconv.FormatFloat(report.Metadata.FinishedAt.Sub(report.Metadata.StartedAt).Seconds(), 'f', 3, 64)
+	suite := junitTestSuite{
+		Name:      "rezip validation of " + report.Metadata.OutputPath,
+		Tests:     len(report.Files) + len(report.Skipped) + len(report.OutputProblems) + len(report.InputProblems),
+		Failures:  report.Summary.Mismatched + len(report.OutputProblems) + len(report.InputProblems),
+		Skipped:   len(report.Skipped),
+		Time:      duration,
+		Timestamp: report.Metadata.StartedAt.Format("2006-01-02T15:04:05"),
+	}
+
+	for _, file := range report.Files {
+		testCase := junitTestCase{Name: file.FileName, ClassName: file.OriginalPath}
+		if !file.Match {
+			testCase.Failure = &junitMessage{
+				Message: "checksum mismatch",
+				Text:    fmt.Sprintf("expected %s %s, got %s", report.Metadata.HashAlgorithm.OrDefault(), file.OriginalSHA, file.NewSHA),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	for _, skipped := range report.Skipped {
+		message := skipped.Reason
+		if skipped.KeptAs != "" {
+			message += ", kept as " + skipped.KeptAs
+		}
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      skipped.Path,
+			ClassName: skipped.Path,
+			Skipped:   &junitMessage{Message: message},
+		})
+	}
+
+	for _, problem := range report.OutputProblems {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      problem.Name,
+			ClassName: "output",
+			Failure:   &junitMessage{Message: problem.Kind, Text: problem.Problem},
+		})
+	}
+
+	for _, problem := range report.InputProblems {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      problem.Path,
+			ClassName: "input",
+			Failure:   &junitMessage{Message: "input problem", Text: problem.Problem},
+		})
+	}
+
+	xmlData, err := xml.MarshalIndent(junitTestSuites{
+		Name:     "rezip",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     duration,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error generating JUnit report: %w", err)
+	}
+
+	_, err = fmt.Fprintf(writer, "%s%s\n", xml.Header, xmlData)
+	return err
+}
+
+func encodeMarkdownReport(writer io.Writer, report validationReport) error {
+	var builder strings.Builder
+
+	status := "valid"
+	if !report.Summary.Valid {
+		status = "invalid"
+	}
+
+	fmt.Fprintf(&builder, "# Validation report\n\n")
+	fmt.Fprintf(&builder, "- **Input:** %s\n", markdownCell(report.Metadata.InputPath))
+	fmt.Fprintf(&builder, "- **Output:** %s\n", markdownCell(report.Metadata.OutputPath))
+	fmt.Fprintf(&builder, "- **Status:** %s\n", status)
+	fmt.Fprintf(&builder, "- **Tool version:** %s\n", markdownCell(report.Metadata.ToolVersion))
+
+	fmt.Fprintf(&builder, "\n## Summary\n\n")
+	fmt.Fprintf(&builder, "| Files | Matched | Mismatched | Skipped | Duplicates | Aliases | Conflicts | Size mismatches |\n")
+	fmt.Fprintf(&builder, "| ---: | ---: | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	fmt.Fprintf(&builder, "| %d | %d | %d | %d | %d | %d | %d | %d |\n",
+		report.Summary.Files, report.Summary.Matched, report.Summary.Mismatched, report.Summary.Skipped,
+		report.Summary.Duplicates, report.Summary.Aliases, report.Summary.Conflicts, report.Summary.SizeMismatches)
+
+	fmt.Fprintf(&builder, "\n## Files\n\n")
+	fmt.Fprintf(&builder, "| File | Original path | Size | Match |\n")
+	fmt.Fprintf(&builder, "| --- | --- | ---: | --- |\n")
+	for _, file := range report.Files {
+		match := "yes"
+		if !file.Match {
+			match = "**no**"
+		}
+		fmt.Fprintf(&builder, "| %s | %s | %d | %s |\n",
+			markdownCell(file.FileName), markdownCell(file.OriginalPath), file.NewSize, match)
+	}
+
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(&builder, "\n## Skipped entries\n\n")
+		fmt.Fprintf(&builder, "| Path | Reason | Kept as |\n")
+		fmt.Fprintf(&builder, "| --- | --- | --- |\n")
+		for _, skipped := range report.Skipped {
+			fmt.Fprintf(&builder, "| %s | %s | %s |\n",
+				markdownCell(skipped.Path), skipped.Reason, markdownCell(skipped.KeptAs))
+		}
+	}
+
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(&builder, "\n## Conflicts\n\n")
+		fmt.Fprintf(&builder, "| Kind | Names | Paths |\n")
+		fmt.Fprintf(&builder, "| --- | --- | --- |\n")
+		for _, conflict := range report.Conflicts {
+			fmt.Fprintf(&builder, "| %s | %s | %s |\n", conflict.Kind,
+				markdownCell(strings.Join(conflict.Names, ", ")), markdownCell(strings.Join(conflict.Paths, ", ")))
+		}
+	}
+
+	if len(report.SizeMismatches) > 0 {
+		fmt.Fprintf(&builder, "\n## Size mismatches\n\n")
+		fmt.Fprintf(&builder, "| Path | Declared size | Actual size |\n")
+		fmt.Fprintf(&builder, "| --- | ---: | ---: |\n")
+		for _, mismatch := range report.SizeMismatches {
+			fmt.Fprintf(&builder, "| %s | %d | %d |\n",
+				markdownCell(mismatch.Path), mismatch.DeclaredSize, mismatch.ActualSize)
+		}
+	}
+
+	if len(report.OutputProblems) > 0 {
+		fmt.Fprintf(&builder, "\n## Output problems\n\n")
+		fmt.Fprintf(&builder, "| Name | Kind | Problem |\n")
+		fmt.Fprintf(&builder, "| --- | --- | --- |\n")
+		for _, problem := range report.OutputProblems {
+			fmt.Fprintf(&builder, "| %s | %s | %s |\n",
+				markdownCell(problem.Name), problem.Kind, markdownCell(problem.Problem))
+		}
+	}
+
+	if len(report.InputProblems) > 0 {
+		fmt.Fprintf(&builder, "\n## Input problems\n\n")
+		fmt.Fprintf(&builder, "| Path | Problem |\n")
+		fmt.Fprintf(&builder, "| --- | --- |\n")
+		for _, problem := range report.InputProblems {
+			fmt.Fprintf(&builder, "| %s | %s |\n", markdownCell(problem.Path), markdownCell(problem.Problem))
+		}
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// markdownCell escapes text so that it is rendered literally inside a Markdown table cell.
+func markdownCell(text string) string {
+	var builder strings.Builder
+	for _, character := range text {
+		switch character {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '|', '#':
+			builder.WriteRune('\\')
+			builder.WriteRune(character)
+		case '\n', '\r':
+			builder.WriteRune(' ')
+		default:
+			builder.WriteRune(character)
+		}
+	}
+	return builder.String()
+}
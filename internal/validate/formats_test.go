@@ -0,0 +1,204 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestParseReportFormat(t *testing.T) {
+	t.Run("Returns error for unknown format", func(t *testing.T) {
+		_, err := ParseReportFormat("html")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown report format "html"`)
+	})
+
+	t.Run("Successfully parses all formats", func(t *testing.T) {
+		for _, value := range []string{"json", "ndjson", "csv", "junit", "markdown"} {
+			format, err := ParseReportFormat(value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ReportFormat(value), format)
+		}
+	})
+}
+
+func TestEncodeReport(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	report := validationReport{
+		Version: reportVersion,
+		Metadata: reportMetadata{
+			ToolVersion:   "v1.2.3",
+			InputPath:     "in.zip",
+			OutputPath:    "out.zip",
+			HashAlgorithm: repackage.HashSHA512,
+			StartedAt:     startedAt,
+			FinishedAt:    startedAt.Add(1500 * time.Millisecond),
+		},
+		Summary: reportSummary{Files: 2, Matched: 1, Mismatched: 1, Skipped: 1, Duplicates: 1, Conflicts: 1},
+		Files: []validationResult{
+			{
+				FileName: "a|b.txt", OriginalPath: "dir/a|b.txt", OriginalSHA: "aa", NewSHA: "aa",
+				OriginalSize: 3, NewSize: 3, Match: true,
+				Aliases: []aliasResult{{FileName: "copy.txt", OriginalPath: "x/copy.txt"}},
+			},
+			{
+				FileName: "logo.png", OriginalPath: "b/logo.png", OriginalSHA: "bb", NewSHA: "cc",
+				OriginalSize: 9, NewSize: 9, Match: false, CaseCollisions: []string{"LOGO.png", "Logo.png"},
+			},
+		},
+		Skipped: []skippedResult{{Path: "a/logo.png", Reason: "duplicate", KeptAs: "logo.png"}},
+		Conflicts: []conflictResult{{
+			Kind: conflictDuplicateName, Names: []string{"logo.png"}, Paths: []string{"b/logo.png", "a/logo.png"}, Kept: "b/logo.png",
+		}},
+		SizeMismatches: []sizeMismatchResult{},
+	}
+
+	encode := func(t *testing.T, format ReportFormat) string {
+		var buf bytes.Buffer
+		require.NoError(t, encodeReport(&buf, report, format))
+		return buf.String()
+	}
+
+	t.Run("Successfully encodes JSON by default", func(t *testing.T) {
+		assert.Equal(t, encode(t, ReportJSON), encode(t, ""))
+
+		var decoded validationReport
+		require.NoError(t, json.Unmarshal([]byte(encode(t, ReportJSON)), &decoded))
+		assert.Equal(t, report, decoded)
+	})
+
+	t.Run("Successfully encodes one NDJSON record per line", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(encode(t, ReportNDJSON), "\n"), "\n")
+
+		require.Len(t, lines, 5)
+		var records []string
+		for _, line := range lines {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			records = append(records, record["record"].(string))
+		}
+		assert.Equal(t, []string{"report", "file", "file", "skipped", "conflict"}, records)
+		assert.Contains(t, lines[0], `"summary":{"valid":false,"files":2,`)
+		assert.Contains(t, lines[2], `"file_name":"logo.png"`)
+		assert.Contains(t, lines[3], `"kept_as":"logo.png"`)
+	})
+
+	t.Run("Successfully encodes one CSV row per file", func(t *testing.T) {
+		records, err := csv.NewReader(strings.NewReader(encode(t, ReportCSV))).ReadAll()
+
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"file_name", "original_path", "original_sha", "new_sha", "original_size", "new_size", "match", "case_collisions", "aliases", "source_sha"},
+			{"a|b.txt", "dir/a|b.txt", "aa", "aa", "3", "3", "true", "", "copy.txt=x/copy.txt", ""},
+			{"logo.png", "b/logo.png", "bb", "cc", "9", "9", "false", "LOGO.png;Logo.png", "", ""},
+		}, records)
+	})
+
+	t.Run("Successfully encodes JUnit test cases", func(t *testing.T) {
+		output := encode(t, ReportJUnit)
+
+		assert.True(t, strings.HasPrefix(output, xml.Header))
+		var suites junitTestSuites
+		require.NoError(t, xml.Unmarshal([]byte(output), &suites))
+
+		assert.Equal(t, 3, suites.Tests)
+		assert.Equal(t, 1, suites.Failures)
+		assert.Equal(t, 1, suites.Skipped)
+		assert.Equal(t, "1.500", suites.Time)
+		require.Len(t, suites.Suites, 1)
+		require.Len(t, suites.Suites[0].TestCases, 3)
+		assert.Equal(t, "2024-05-01T12:00:00", suites.Suites[0].Timestamp)
+
+		passed, failed, skipped := suites.Suites[0].TestCases[0], suites.Suites[0].TestCases[1], suites.Suites[0].TestCases[2]
+		assert.Equal(t, junitTestCase{Name: "a|b.txt", ClassName: "dir/a|b.txt"}, passed)
+		require.NotNil(t, failed.Failure)
+		assert.Equal(t, "checksum mismatch", failed.Failure.Message)
+		assert.Equal(t, "expected sha512 bb, got cc", failed.Failure.Text)
+		require.NotNil(t, skipped.Skipped)
+		assert.Equal(t, "duplicate, kept as logo.png", skipped.Skipped.Message)
+	})
+
+	t.Run("Successfully encodes Markdown tables", func(t *testing.T) {
+		output := encode(t, ReportMarkdown)
+
+		assert.True(t, strings.HasPrefix(output, "# Validation report\n"))
+		assert.Contains(t, output, "- **Status:** invalid\n")
+		assert.Contains(t, output, "| 2 | 1 | 1 | 1 | 1 | 0 | 1 | 0 |\n")
+		assert.Contains(t, output, "| a\\|b.txt | dir/a\\|b.txt | 3 | yes |\n")
+		assert.Contains(t, output, "| logo.png | b/logo.png | 9 | **no** |\n")
+		assert.Contains(t, output, "## Skipped entries\n")
+		assert.Contains(t, output, "| duplicate_name | logo.png | b/logo.png, a/logo.png |\n")
+		assert.NotContains(t, output, "## Size mismatches")
+		assert.NotContains(t, output, "## Input problems")
+	})
+
+	t.Run("Successfully encodes input problems", func(t *testing.T) {
+		problemReport := validationReport{
+			Version:       reportVersion,
+			Summary:       reportSummary{InputProblems: 1},
+			InputProblems: []inputProblem{{Path: "b/foo.txt", Problem: "input entry is neither in the output nor reported as skipped"}},
+		}
+		encodeProblems := func(format ReportFormat) string {
+			var buf bytes.Buffer
+			require.NoError(t, encodeReport(&buf, problemReport, format))
+			return buf.String()
+		}
+
+		assert.Contains(t, encodeProblems(ReportNDJSON),
+			`{"record":"input_problem","path":"b/foo.txt","problem":"input entry is neither in the output nor reported as skipped"}`)
+		assert.Contains(t, encodeProblems(ReportMarkdown),
+			"## Input problems\n\n| Path | Problem |\n| --- | --- |\n| b/foo.txt | input entry is neither in the output nor reported as skipped |\n")
+
+		var suites junitTestSuites
+		require.NoError(t, xml.Unmarshal([]byte(encodeProblems(ReportJUnit)), &suites))
+		assert.Equal(t, 1, suites.Failures)
+		require.Len(t, suites.Suites[0].TestCases, 1)
+		assert.Equal(t, "input", suites.Suites[0].TestCases[0].ClassName)
+		require.NotNil(t, suites.Suites[0].TestCases[0].Failure)
+		assert.Equal(t, "input problem", suites.Suites[0].TestCases[0].Failure.Message)
+	})
+
+	t.Run("Successfully encodes output problems", func(t *testing.T) {
+		problemReport := validationReport{
+			Version:        reportVersion,
+			Summary:        reportSummary{OutputProblems: 1},
+			OutputProblems: []outputProblem{{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"}},
+		}
+		encodeProblems := func(format ReportFormat) string {
+			var buf bytes.Buffer
+			require.NoError(t, encodeReport(&buf, problemReport, format))
+			return buf.String()
+		}
+
+		assert.Contains(t, encodeProblems(ReportNDJSON),
+			`{"record":"output_problem","name":"extra.txt","kind":"unexpected","problem":"entry is not part of the repackaging result"}`)
+		assert.Contains(t, encodeProblems(ReportMarkdown),
+			"## Output problems\n\n| Name | Kind | Problem |\n| --- | --- | --- |\n| extra.txt | unexpected | entry is not part of the repackaging result |\n")
+
+		var suites junitTestSuites
+		require.NoError(t, xml.Unmarshal([]byte(encodeProblems(ReportJUnit)), &suites))
+		assert.Equal(t, 1, suites.Failures)
+		require.Len(t, suites.Suites[0].TestCases, 1)
+		assert.Equal(t, "output", suites.Suites[0].TestCases[0].ClassName)
+		require.NotNil(t, suites.Suites[0].TestCases[0].Failure)
+		assert.Equal(t, outputProblemUnexpected, suites.Suites[0].TestCases[0].Failure.Message)
+	})
+}
+
+func TestMarkdownCell(t *testing.T) {
+	t.Run("Successfully escapes Markdown syntax", func(t *testing.T) {
+		assert.Equal(t, "plain name.txt", markdownCell("plain name.txt"))
+		assert.Equal(t, "a\\|b \\*c\\* \\`d\\` \\_e\\_", markdownCell("a|b *c* `d` _e_"))
+		assert.Equal(t, "line one line two", markdownCell("line one\nline two"))
+	})
+}
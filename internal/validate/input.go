@@ -0,0 +1,164 @@
+package validate
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+// inputProblem describes an inconsistency between the output ZIP and the input archive.
+type inputProblem struct {
+	// Path is the path of the input entry involved in the problem.
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+// inputEntry is an entry of the input archives along with its path in the repackaging result.
+type inputEntry struct {
+	path string
+	file *zip.File
+}
+
+// verifyInput reopens the input archives to check, independently from the checksums computed
+// while repackaging, that:
+//   - every output file has the content of the input entry at its original path,
+//   - every other input entry is reported as skipped, and
+//   - every duplicate was dropped according to the deduplication rules.
+//
+// The checksum of the source entry of each output file is recorded in the results.
+func verifyInput(ctx context.Context, inputZipPaths []string, result *repackage.Result, results []validationResult,
+	hashes *repackage.HashCache) ([]inputProblem, error) {
+	var entries []inputEntry
+	for _, inputZipPath := range inputZipPaths {
+		zipReader, err := zip.OpenReader(inputZipPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input zip %s: %w", inputZipPath, err)
+		}
+		defer zipReader.Close()
+
+		for _, file := range zipReader.File {
+			entries = append(entries, inputEntry{
+				path: repackage.EntryPath(inputZipPath, file.Name, len(inputZipPaths) > 1),
+				file: file,
+			})
+		}
+	}
+
+	// Archives may contain several entries with the same name, so all of them are candidates.
+	inputFiles := make(map[string][]*zip.File, len(entries))
+	for _, entry := range entries {
+		inputFiles[entry.path] = append(inputFiles[entry.path], entry.file)
+	}
+
+	var problems []inputProblem
+	keptPaths := make(map[string]bool, len(results))
+	resultsByName := make(map[string]validationResult, len(results))
+
+	for index, fileResult := range results {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		keptPaths[fileResult.OriginalPath] = true
+		resultsByName[fileResult.FileName] = fileResult
+
+		sourceFiles := inputFiles[fileResult.OriginalPath]
+		if len(sourceFiles) == 0 {
+			problems = append(problems, inputProblem{
+				Path:    fileResult.OriginalPath,
+				Problem: fmt.Sprintf("source entry of output file %q is missing from the input", fileResult.FileName),
+			})
+			continue
+		}
+
+		algorithm := result.Files[fileResult.FileName].HashAlgorithm
+		for _, sourceFile := range sourceFiles {
+			_, sourceHash, err := hashes.Measure(sourceFile, algorithm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute hash for input file '%s': %w", sourceFile.Name, err)
+			}
+
+			results[index].SourceSHA = sourceHash.String()
+			if results[index].SourceSHA == fileResult.NewSHA {
+				break
+			}
+		}
+		if results[index].SourceSHA != fileResult.NewSHA {
+			problems = append(problems, inputProblem{
+				Path:    fileResult.OriginalPath,
+				Problem: fmt.Sprintf("content of output file %q differs from its source entry", fileResult.FileName),
+			})
+		}
+	}
+
+	skippedEntries := make(map[string]repackage.SkippedEntry, len(result.Skipped))
+	for _, skipped := range result.Skipped {
+		skippedEntries[skipped.Path] = skipped
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if keptPaths[entry.path] {
+			continue
+		}
+
+		skipped, isSkipped := skippedEntries[entry.path]
+		if !isSkipped && entry.file.FileInfo().IsDir() {
+			continue
+		}
+		if !isSkipped {
+			problems = append(problems, inputProblem{
+				Path:    entry.path,
+				Problem: "input entry is neither in the output nor reported as skipped",
+			})
+			continue
+		}
+
+		if skipped.Reason != repackage.SkipReasonDuplicate && skipped.Reason != repackage.SkipReasonDuplicateContent {
+			continue
+		}
+
+		problem, err := checkDuplicate(entry.file, skipped, resultsByName, result.Files[skipped.KeptAs].HashAlgorithm, hashes)
+		if err != nil {
+			return nil, err
+		}
+		if problem != "" {
+			problems = append(problems, inputProblem{Path: entry.path, Problem: problem})
+		}
+	}
+
+	return problems, nil
+}
+
+// checkDuplicate checks that a dropped duplicate satisfies the deduplication rule of its reason
+// against the output file kept instead, whose checksum was computed with the algorithm, and
+// returns the violation, if any.
+func checkDuplicate(file *zip.File, skipped repackage.SkippedEntry, resultsByName map[string]validationResult,
+	algorithm repackage.HashAlgorithm, hashes *repackage.HashCache) (string, error) {
+	kept, exists := resultsByName[skipped.KeptAs]
+	if !exists {
+		return fmt.Sprintf("file %q kept instead of this duplicate is missing from the output", skipped.KeptAs), nil
+	}
+
+	size, hash, err := hashes.Measure(file, algorithm)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute hash for input file '%s': %w", file.Name, err)
+	}
+	isSameContent := hash.String() == kept.NewSHA
+
+	switch {
+	case skipped.Reason == repackage.SkipReasonDuplicateContent && !isSameContent:
+		return fmt.Sprintf("content differs from file %q it was deduplicated into", skipped.KeptAs), nil
+	case size > kept.NewSize:
+		return fmt.Sprintf("duplicate is larger than file %q kept instead", skipped.KeptAs), nil
+	case size == kept.NewSize && !isSameContent:
+		return fmt.Sprintf("duplicate has the size of file %q kept instead but different content", skipped.KeptAs), nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,166 @@
+package validate
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestVerifyInput(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.zip")
+	makeTestZip(t, inputPath, map[string]string{
+		"a/foo.txt":      "small",
+		"b/foo.txt":      "larger content",
+		"c/foo-copy.txt": "larger content",
+		"docs/bar.txt":   "bar",
+		".DS_Store":      "metadata",
+	})
+
+	// repackageAndValidate repackages the input and returns the validation results of its output.
+	repackageAndValidate := func(t *testing.T, outputName string) (*repackage.Result, []validationResult) {
+		outputPath := filepath.Join(tempDir, outputName)
+		result, err := repackage.Run(context.Background(), []string{inputPath}, outputPath, repackage.Options{
+			DedupeContent: true,
+			CanonicalRule: repackage.CanonicalShortest,
+		})
+		require.NoError(t, err)
+
+		zipReader, actualFiles, err := readOutputZip(outputPath)
+		require.NoError(t, err)
+		defer zipReader.Close()
+
+		results, allMatch, err := validateFileHashes(actualFiles, result.Files, repackage.NewHashCache())
+		require.NoError(t, err)
+		require.True(t, allMatch)
+
+		return result, results
+	}
+
+	t.Run("Returns error when can't open input zip", func(t *testing.T) {
+		problems, err := verifyInput(context.Background(), []string{filepath.Join(tempDir, "nonexistent.zip")}, &repackage.Result{}, nil, repackage.NewHashCache())
+
+		assert.Error(t, err)
+		assert.Nil(t, problems)
+		assert.Contains(t, err.Error(), "failed to open input zip")
+	})
+
+	t.Run("Successfully verifies a consistent output against its input", func(t *testing.T) {
+		result, results := repackageAndValidate(t, "consistent_output.zip")
+
+		problems, err := verifyInput(context.Background(), []string{inputPath}, result, results, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Empty(t, problems)
+		for _, fileResult := range results {
+			assert.Equal(t, fileResult.NewSHA, fileResult.SourceSHA, "Source checksum of %s", fileResult.FileName)
+		}
+	})
+
+	t.Run("Successfully verifies an output merged from several inputs", func(t *testing.T) {
+		otherInputPath := filepath.Join(tempDir, "other_input.zip")
+		makeTestZip(t, otherInputPath, map[string]string{
+			"lib/foo.txt":   "larger content",
+			"lib/extra.txt": "extra",
+		})
+		outputPath := filepath.Join(tempDir, "merged_output.zip")
+		result, err := repackage.Run(context.Background(), []string{inputPath, otherInputPath}, outputPath, repackage.Options{})
+		require.NoError(t, err)
+
+		zipReader, actualFiles, err := readOutputZip(outputPath)
+		require.NoError(t, err)
+		defer zipReader.Close()
+		results, allMatch, err := validateFileHashes(actualFiles, result.Files, repackage.NewHashCache())
+		require.NoError(t, err)
+		require.True(t, allMatch)
+
+		problems, err := verifyInput(context.Background(), []string{inputPath, otherInputPath}, result, results, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("Successfully detects a wrong source entry", func(t *testing.T) {
+		result, results := repackageAndValidate(t, "wrong_source_output.zip")
+		for index := range results {
+			if results[index].FileName == "foo.txt" {
+				results[index].OriginalPath = "a/foo.txt"
+			}
+		}
+
+		problems, err := verifyInput(context.Background(), []string{inputPath}, result, results, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []inputProblem{
+			{Path: "a/foo.txt", Problem: `content of output file "foo.txt" differs from its source entry`},
+			{Path: "b/foo.txt", Problem: "input entry is neither in the output nor reported as skipped"},
+		}, problems)
+	})
+
+	t.Run("Successfully detects a source entry missing from the input", func(t *testing.T) {
+		result, results := repackageAndValidate(t, "missing_source_output.zip")
+		for index := range results {
+			if results[index].FileName == "bar.txt" {
+				results[index].OriginalPath = "docs/other.txt"
+			}
+		}
+
+		problems, err := verifyInput(context.Background(), []string{inputPath}, result, results, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []inputProblem{
+			{Path: "docs/other.txt", Problem: `source entry of output file "bar.txt" is missing from the input`},
+			{Path: "docs/bar.txt", Problem: "input entry is neither in the output nor reported as skipped"},
+		}, problems)
+	})
+}
+
+func TestCheckDuplicate(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.zip")
+	makeTestZip(t, inputPath, map[string]string{"dup.txt": "content"})
+
+	zipReader, err := zip.OpenReader(inputPath)
+	require.NoError(t, err)
+	defer zipReader.Close()
+	duplicateFile := zipReader.File[0]
+
+	contentHash := sha256.Sum256([]byte("content"))
+	otherHash := sha256.Sum256([]byte("CONTENT"))
+	resultsByName := map[string]validationResult{
+		"same.txt":    {FileName: "same.txt", NewSHA: hex.EncodeToString(contentHash[:]), NewSize: 7},
+		"smaller.txt": {FileName: "smaller.txt", NewSHA: hex.EncodeToString(otherHash[:]), NewSize: 3},
+		"other.txt":   {FileName: "other.txt", NewSHA: hex.EncodeToString(otherHash[:]), NewSize: 7},
+		"larger.txt":  {FileName: "larger.txt", NewSHA: hex.EncodeToString(otherHash[:]), NewSize: 20},
+	}
+
+	for _, testCase := range []struct {
+		reason          repackage.SkipReason
+		keptAs          string
+		expectedProblem string
+	}{
+		{repackage.SkipReasonDuplicate, "same.txt", ""},
+		{repackage.SkipReasonDuplicate, "larger.txt", ""},
+		{repackage.SkipReasonDuplicate, "smaller.txt", `duplicate is larger than file "smaller.txt" kept instead`},
+		{repackage.SkipReasonDuplicate, "other.txt", `duplicate has the size of file "other.txt" kept instead but different content`},
+		{repackage.SkipReasonDuplicate, "missing.txt", `file "missing.txt" kept instead of this duplicate is missing from the output`},
+		{repackage.SkipReasonDuplicateContent, "same.txt", ""},
+		{repackage.SkipReasonDuplicateContent, "larger.txt", `content differs from file "larger.txt" it was deduplicated into`},
+	} {
+		t.Run("Successfully checks "+string(testCase.reason)+" kept as "+testCase.keptAs, func(t *testing.T) {
+			skipped := repackage.SkippedEntry{Path: "dup.txt", Reason: testCase.reason, KeptAs: testCase.keptAs}
+
+			problem, err := checkDuplicate(duplicateFile, skipped, resultsByName, repackage.HashSHA256, repackage.NewHashCache())
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedProblem, problem)
+		})
+	}
+}
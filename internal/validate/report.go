@@ -0,0 +1,333 @@
+package validate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+	"github.com/yash15112001/rezip/internal/version"
+)
+
+// reportVersion is the version of the validation report format. It is incremented whenever a
+// change could break existing consumers, such as removing or renaming a field.
+const reportVersion = 1
+
+// ReportSchema is the JSON Schema describing the validation report.
+//
+//go:embed report.schema.json
+var ReportSchema []byte
+
+// Conflict kinds listed in the validation report.
+const (
+	// conflictDuplicateName groups the input entries flattened to the same output name.
+	conflictDuplicateName = "duplicate_name"
+
+	// conflictCaseCollision groups the output names that only differ by letter case.
+	conflictCaseCollision = "case_collision"
+)
+
+// validationReport is the document written by the validation.
+type validationReport struct {
+	Version   int                `json:"version"`
+	Metadata  reportMetadata     `json:"metadata"`
+	Summary   reportSummary      `json:"summary"`
+	Files     []validationResult `json:"files"`
+	Skipped   []skippedResult    `json:"skipped"`
+	Conflicts []conflictResult   `json:"conflicts"`
+
+	SizeMismatches []sizeMismatchResult `json:"size_mismatches"`
+
+	// OutputProblems lists the entries of the output ZIP that are unexpected, listed more than
+	// once, or whose data disagrees with their header.
+	OutputProblems []outputProblem `json:"output_problems"`
+
+	// InputProblems lists the inconsistencies found by checking the output against the input
+	// archive. It is only present when the input was verified.
+	InputProblems []inputProblem `json:"input_problems,omitempty"`
+
+	// Manifest is the manifest embedded in the output ZIP, so that a signed report also covers
+	// it. It is only present when a manifest was embedded.
+	Manifest *manifestResult `json:"manifest,omitempty"`
+}
+
+// manifestResult represents the manifest embedded in the output ZIP.
+type manifestResult struct {
+	FileName string `json:"file_name"`
+	SHA      string `json:"sha"`
+	Size     int64  `json:"size"`
+}
+
+// reportMetadata describes the run that produced the output ZIP.
+type reportMetadata struct {
+	ToolVersion string `json:"tool_version"`
+	InputPath   string `json:"input_path"`
+
+	// MergedPaths are the archives merged with the input archive, if any.
+	MergedPaths []string `json:"merged_paths,omitempty"`
+
+	OutputPath string `json:"output_path"`
+
+	// HashAlgorithm is the algorithm of the checksums of the report. Reports written before it
+	// could be selected omit it and use SHA-256.
+	HashAlgorithm repackage.HashAlgorithm `json:"hash_algorithm"`
+
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Options    reportOptions `json:"options"`
+
+	// InputVerified is true when the output was checked against the input archive.
+	InputVerified bool `json:"input_verified"`
+}
+
+// reportOptions lists the repackaging options of the run. Options left to their default value
+// are omitted.
+type reportOptions struct {
+	VerifySizes        bool     `json:"verify_sizes"`
+	NamePolicy         string   `json:"names,omitempty"`
+	Normalization      string   `json:"normalize,omitempty"`
+	LegacyEncoding     string   `json:"legacy_encoding,omitempty"`
+	CaseInsensitive    bool     `json:"case_insensitive"`
+	WarnCaseCollisions bool     `json:"warn_case_collisions"`
+	DedupeContent      bool     `json:"dedupe_content"`
+	CanonicalRule      string   `json:"canonical,omitempty"`
+	KeepDepth          int      `json:"keep_depth,omitempty"`
+	StripPrefix        string   `json:"strip_prefix,omitempty"`
+	RewriteRules       []string `json:"rewrite,omitempty"`
+	RenameTemplate     string   `json:"rename,omitempty"`
+	EmbedManifest      bool     `json:"embed_manifest"`
+	ManifestFormat     string   `json:"manifest_format,omitempty"`
+}
+
+// reportSummary counts the entries of each section of the report.
+type reportSummary struct {
+	// Valid is true when every output file matches the checksum of its input entry, no output
+	// problem was found and, when the input was verified, no input problem was found.
+	Valid bool `json:"valid"`
+
+	Files          int `json:"files"`
+	Matched        int `json:"matched"`
+	Mismatched     int `json:"mismatched"`
+	Skipped        int `json:"skipped"`
+	Duplicates     int `json:"duplicates"`
+	Aliases        int `json:"aliases"`
+	Conflicts      int `json:"conflicts"`
+	SizeMismatches int `json:"size_mismatches"`
+	OutputProblems int `json:"output_problems"`
+	InputProblems  int `json:"input_problems"`
+}
+
+// skippedResult represents an input entry that was not written to the output ZIP.
+type skippedResult struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+
+	// KeptAs is the name of the output file kept instead of a duplicate entry.
+	KeptAs string `json:"kept_as,omitempty"`
+}
+
+// conflictResult represents a group of entries whose names clashed during flattening.
+type conflictResult struct {
+	Kind string `json:"kind"`
+
+	// Names lists the output names involved in the conflict.
+	Names []string `json:"names"`
+
+	// Paths lists the input paths of the entries involved in the conflict.
+	Paths []string `json:"paths"`
+
+	// Kept is the input path of the entry written to the output ZIP for a duplicate name.
+	Kept string `json:"kept,omitempty"`
+}
+
+// sizeMismatchResult represents an input entry whose header declares a wrong size.
+type sizeMismatchResult struct {
+	Path         string `json:"path"`
+	DeclaredSize int64  `json:"declared_size"`
+	ActualSize   int64  `json:"actual_size"`
+}
+
+// buildValidationReport assembles the report of a run from the repackaging result, the
+// validation results, which must be sorted by file name, and the problems found in the output
+// entries and by verifying the input.
+func buildValidationReport(outputZipPath string, result *repackage.Result, results []validationResult,
+	outputProblems []outputProblem, inputProblems []inputProblem, options Options, startedAt time.Time) validationReport {
+	report := validationReport{
+		Version: reportVersion,
+		Metadata: reportMetadata{
+			ToolVersion: version.Version,
+			InputPath:   options.InputZipPath,
+			MergedPaths: options.MergedZipPaths,
+			OutputPath:  outputZipPath,
+
+			HashAlgorithm: options.RepackageOptions.HashAlgorithm.OrDefault(),
+
+			StartedAt:  startedAt.UTC(),
+			FinishedAt: time.Now().UTC(),
+			Options:    buildReportOptions(options.RepackageOptions),
+
+			InputVerified: options.VerifyInput,
+		},
+		Files:          results,
+		Skipped:        make([]skippedResult, 0, len(result.Skipped)),
+		Conflicts:      make([]conflictResult, 0),
+		SizeMismatches: make([]sizeMismatchResult, 0, len(result.SizeMismatches)),
+		OutputProblems: make([]outputProblem, 0, len(outputProblems)),
+		InputProblems:  inputProblems,
+	}
+	report.OutputProblems = append(report.OutputProblems, outputProblems...)
+
+	for _, skipped := range result.Skipped {
+		report.Skipped = append(report.Skipped, skippedResult{
+			Path:   skipped.Path,
+			Reason: string(skipped.Reason),
+			KeptAs: skipped.KeptAs,
+		})
+	}
+
+	for _, fileResult := range results {
+		fileInfo := result.Files[fileResult.FileName]
+		if len(fileInfo.Duplicates) > 0 {
+			report.Conflicts = append(report.Conflicts, conflictResult{
+				Kind:  conflictDuplicateName,
+				Names: []string{fileResult.FileName},
+				Paths: append([]string{fileInfo.OriginalPath}, fileInfo.Duplicates...),
+				Kept:  fileInfo.OriginalPath,
+			})
+		}
+
+		report.Summary.Duplicates += len(fileInfo.Duplicates)
+		report.Summary.Aliases += len(fileInfo.Aliases)
+		if fileResult.Match {
+			report.Summary.Matched++
+		} else {
+			report.Summary.Mismatched++
+		}
+	}
+
+	for _, names := range result.CaseCollisions {
+		paths := make([]string, 0, len(names))
+		for _, name := range names {
+			paths = append(paths, result.Files[name].OriginalPath)
+		}
+		report.Conflicts = append(report.Conflicts, conflictResult{
+			Kind:  conflictCaseCollision,
+			Names: names,
+			Paths: paths,
+		})
+	}
+
+	for _, mismatch := range result.SizeMismatches {
+		report.SizeMismatches = append(report.SizeMismatches, sizeMismatchResult{
+			Path:         mismatch.Path,
+			DeclaredSize: mismatch.DeclaredSize,
+			ActualSize:   mismatch.ActualSize,
+		})
+	}
+
+	report.Summary.Valid = report.Summary.Mismatched == 0 && len(outputProblems) == 0 && len(inputProblems) == 0
+	report.Summary.Files = len(results)
+	report.Summary.Skipped = len(report.Skipped)
+	report.Summary.Conflicts = len(report.Conflicts)
+	report.Summary.SizeMismatches = len(report.SizeMismatches)
+	report.Summary.OutputProblems = len(outputProblems)
+	report.Summary.InputProblems = len(inputProblems)
+
+	return report
+}
+
+// buildReportOptions converts repackaging options into their report representation.
+func buildReportOptions(options repackage.Options) reportOptions {
+	reported := reportOptions{
+		VerifySizes:        options.VerifySizes,
+		NamePolicy:         string(options.NamePolicy),
+		Normalization:      string(options.Normalization),
+		LegacyEncoding:     string(options.LegacyEncoding),
+		CaseInsensitive:    options.CaseInsensitive,
+		WarnCaseCollisions: options.WarnCaseCollisions,
+		DedupeContent:      options.DedupeContent,
+		CanonicalRule:      string(options.CanonicalRule),
+		KeepDepth:          options.KeepDepth,
+		StripPrefix:        options.StripPrefix,
+		EmbedManifest:      options.EmbedManifest,
+		ManifestFormat:     string(options.ManifestFormat),
+	}
+
+	for _, rule := range options.RewriteRules {
+		reported.RewriteRules = append(reported.RewriteRules, rule.String())
+	}
+	if options.RenameTemplate != nil {
+		reported.RenameTemplate = options.RenameTemplate.String()
+	}
+
+	return reported
+}
+
+// ReportedFile is a file of the output ZIP as listed by a validation report.
+type ReportedFile struct {
+	repackage.FileInfo
+
+	// CRC32 is the CRC-32 of the file in the output ZIP, or zero when the report does not
+	// record it.
+	CRC32 uint32
+}
+
+// ReadReportFiles reads the files of the output ZIP listed by a JSON validation report, so that
+// an incremental update or a comparison can reuse their checksums. Files whose checksum did not
+// match are left out.
+func ReadReportFiles(reportPath string) (map[string]ReportedFile, error) {
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation report: %w", err)
+	}
+
+	report, algorithm, err := parseReport(content)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]ReportedFile, len(report.Files))
+	for _, fileResult := range report.Files {
+		hash, err := algorithm.ParseDigest(fileResult.NewSHA)
+		if !fileResult.Match || err != nil {
+			continue
+		}
+
+		// Reports written before the CRC-32 was recorded omit it.
+		crc32, _ := strconv.ParseUint(fileResult.NewCRC32, 16, 32)
+
+		files[fileResult.FileName] = ReportedFile{
+			FileInfo: repackage.FileInfo{
+				OriginalPath:  fileResult.OriginalPath,
+				HashAlgorithm: algorithm,
+				Hash:          hash,
+				Size:          fileResult.NewSize,
+			},
+			CRC32: uint32(crc32),
+		}
+	}
+
+	return files, nil
+}
+
+// parseReport parses a JSON validation report of the current version and returns it along with
+// the algorithm of its checksums.
+func parseReport(content []byte) (validationReport, repackage.HashAlgorithm, error) {
+	var report validationReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return validationReport{}, "", fmt.Errorf("failed to parse validation report: %w", err)
+	}
+	if report.Version != reportVersion {
+		return validationReport{}, "", fmt.Errorf("unsupported validation report version %d: expected %d", report.Version, reportVersion)
+	}
+
+	algorithm, err := repackage.ParseHashAlgorithm(string(report.Metadata.HashAlgorithm.OrDefault()))
+	if err != nil {
+		return validationReport{}, "", fmt.Errorf("unsupported validation report: %w", err)
+	}
+
+	return report, algorithm, nil
+}
@@ -0,0 +1,276 @@
+package validate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestBuildValidationReport(t *testing.T) {
+	result := &repackage.Result{
+		Files: map[string]repackage.FileInfo{
+			"README.md": {OriginalPath: "docs/README.md", CaseCollisions: []string{"readme.md"}},
+			"readme.md": {OriginalPath: "src/readme.md", CaseCollisions: []string{"README.md"}},
+			"logo.png": {
+				OriginalPath: "b/logo.png",
+				Duplicates:   []string{"a/logo.png"},
+				Aliases:      []repackage.Alias{{Name: "logo-copy.png", OriginalPath: "c/logo-copy.png"}},
+			},
+		},
+		Skipped: []repackage.SkippedEntry{
+			{Path: ".DS_Store", Reason: repackage.SkipReasonMetadata},
+			{Path: "a/logo.png", Reason: repackage.SkipReasonDuplicate, KeptAs: "logo.png"},
+			{Path: "c/logo-copy.png", Reason: repackage.SkipReasonDuplicateContent, KeptAs: "logo.png"},
+		},
+		SizeMismatches: []repackage.SizeMismatch{{Path: "b/logo.png", DeclaredSize: 1, ActualSize: 9}},
+		CaseCollisions: [][]string{{"README.md", "readme.md"}},
+	}
+	results := []validationResult{
+		{FileName: "README.md", Match: true},
+		{FileName: "logo.png", Match: false},
+		{FileName: "readme.md", Match: true},
+	}
+
+	t.Run("Successfully records the run metadata", func(t *testing.T) {
+		startedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		rename, err := repackage.ParseRenameTemplate("{parent}_{base}")
+		require.NoError(t, err)
+		rewrite, err := repackage.ParseRewriteRule("^v[0-9]+/=>")
+		require.NoError(t, err)
+
+		report := buildValidationReport("out.zip", result, results, nil, nil, Options{
+			InputZipPath: "in.zip",
+			RepackageOptions: repackage.Options{
+				NamePolicy:     repackage.NamePolicyWindows,
+				RenameTemplate: rename,
+				RewriteRules:   []repackage.RewriteRule{rewrite},
+			},
+		}, startedAt)
+
+		assert.Equal(t, reportVersion, report.Version)
+		assert.Equal(t, "in.zip", report.Metadata.InputPath)
+		assert.Equal(t, "out.zip", report.Metadata.OutputPath)
+		assert.Equal(t, startedAt.UTC(), report.Metadata.StartedAt)
+		assert.False(t, report.Metadata.FinishedAt.Before(report.Metadata.StartedAt))
+		assert.NotEmpty(t, report.Metadata.ToolVersion)
+		assert.Equal(t, reportOptions{
+			NamePolicy:     "windows",
+			RewriteRules:   []string{"^v[0-9]+/=>"},
+			RenameTemplate: "{parent}_{base}",
+		}, report.Metadata.Options)
+	})
+
+	t.Run("Successfully summarizes the run", func(t *testing.T) {
+		report := buildValidationReport("out.zip", result, results, nil, nil, Options{}, time.Now())
+
+		assert.Equal(t, reportSummary{
+			Valid:          false,
+			Files:          3,
+			Matched:        2,
+			Mismatched:     1,
+			Skipped:        3,
+			Duplicates:     1,
+			Aliases:        1,
+			Conflicts:      2,
+			SizeMismatches: 1,
+		}, report.Summary)
+	})
+
+	t.Run("Successfully lists skipped entries, conflicts and size mismatches", func(t *testing.T) {
+		report := buildValidationReport("out.zip", result, results, nil, nil, Options{}, time.Now())
+
+		assert.Equal(t, []skippedResult{
+			{Path: ".DS_Store", Reason: "metadata"},
+			{Path: "a/logo.png", Reason: "duplicate", KeptAs: "logo.png"},
+			{Path: "c/logo-copy.png", Reason: "duplicate_content", KeptAs: "logo.png"},
+		}, report.Skipped)
+		assert.Equal(t, []conflictResult{
+			{Kind: conflictDuplicateName, Names: []string{"logo.png"}, Paths: []string{"b/logo.png", "a/logo.png"}, Kept: "b/logo.png"},
+			{Kind: conflictCaseCollision, Names: []string{"README.md", "readme.md"}, Paths: []string{"docs/README.md", "src/readme.md"}},
+		}, report.Conflicts)
+		assert.Equal(t, []sizeMismatchResult{{Path: "b/logo.png", DeclaredSize: 1, ActualSize: 9}}, report.SizeMismatches)
+	})
+
+	t.Run("Successfully uses empty lists without skipped entries or conflicts", func(t *testing.T) {
+		report := buildValidationReport("out.zip", &repackage.Result{}, []validationResult{}, nil, nil, Options{}, time.Now())
+
+		jsonData, err := json.Marshal(report)
+		require.NoError(t, err)
+
+		assert.True(t, report.Summary.Valid)
+		assert.Contains(t, string(jsonData), `"files":[],"skipped":[],"conflicts":[],"size_mismatches":[],"output_problems":[]`)
+	})
+
+	t.Run("Successfully marks the report invalid when the output has problems", func(t *testing.T) {
+		problems := []outputProblem{{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"}}
+
+		report := buildValidationReport("out.zip", &repackage.Result{}, []validationResult{}, problems, nil, Options{}, time.Now())
+
+		assert.False(t, report.Summary.Valid)
+		assert.Equal(t, 1, report.Summary.OutputProblems)
+		assert.Equal(t, problems, report.OutputProblems)
+	})
+}
+
+func TestReadReportFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("Returns error when the report doesn't exist", func(t *testing.T) {
+		files, err := ReadReportFiles(filepath.Join(tempDir, "missing.json"))
+
+		assert.Error(t, err)
+		assert.Nil(t, files)
+		assert.Contains(t, err.Error(), "failed to read validation report")
+	})
+
+	t.Run("Returns error when the report has another version", func(t *testing.T) {
+		reportPath := filepath.Join(tempDir, "future.json")
+		require.NoError(t, os.WriteFile(reportPath, []byte(`{"version": 99, "files": []}`), 0o644))
+
+		files, err := ReadReportFiles(reportPath)
+
+		assert.Error(t, err)
+		assert.Nil(t, files)
+		assert.Contains(t, err.Error(), "unsupported validation report version 99")
+	})
+
+	t.Run("Successfully reads the matching files of a report", func(t *testing.T) {
+		contentHash := sha256.Sum256([]byte("content"))
+		report := validationReport{
+			Version: reportVersion,
+			Files: []validationResult{
+				{FileName: "kept.txt", OriginalPath: "a/kept.txt", NewSHA: hex.EncodeToString(contentHash[:]), NewSize: 7, Match: true, NewCRC32: "0a1b2c3d"},
+				{FileName: "legacy.txt", OriginalPath: "a/legacy.txt", NewSHA: hex.EncodeToString(contentHash[:]), NewSize: 7, Match: true},
+				{FileName: "mismatched.txt", OriginalPath: "a/mismatched.txt", NewSHA: hex.EncodeToString(contentHash[:]), NewSize: 7},
+				{FileName: "corrupt.txt", OriginalPath: "a/corrupt.txt", Match: true},
+			},
+		}
+		reportPath := filepath.Join(tempDir, "report.json")
+		require.NoError(t, writeValidationReport(report, reportPath, ReportJSON, nil))
+
+		files, err := ReadReportFiles(reportPath)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]ReportedFile{
+			"kept.txt": {
+				FileInfo: repackage.FileInfo{OriginalPath: "a/kept.txt", HashAlgorithm: repackage.HashSHA256, Hash: repackage.Digest(contentHash[:]), Size: 7},
+				CRC32:    0x0a1b2c3d,
+			},
+			"legacy.txt": {
+				FileInfo: repackage.FileInfo{OriginalPath: "a/legacy.txt", HashAlgorithm: repackage.HashSHA256, Hash: repackage.Digest(contentHash[:]), Size: 7},
+			},
+		}, files)
+	})
+}
+
+func TestReportSchema(t *testing.T) {
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(ReportSchema, &schema), "Schema should be valid JSON")
+
+	t.Run("Successfully describes generated reports", func(t *testing.T) {
+		hash := strings.Repeat("ab", 32)
+		report := buildValidationReport("out.zip", &repackage.Result{
+			Files: map[string]repackage.FileInfo{
+				"logo.png": {
+					OriginalPath:   "b/logo.png",
+					Duplicates:     []string{"a/logo.png"},
+					CaseCollisions: []string{"LOGO.png"},
+					Aliases:        []repackage.Alias{{Name: "logo-copy.png", OriginalPath: "c/logo-copy.png"}},
+				},
+			},
+			Skipped:        []repackage.SkippedEntry{{Path: "a/logo.png", Reason: repackage.SkipReasonDuplicate, KeptAs: "logo.png"}},
+			SizeMismatches: []repackage.SizeMismatch{{Path: "b/logo.png", DeclaredSize: 1, ActualSize: 9}},
+			CaseCollisions: [][]string{{"LOGO.png", "logo.png"}},
+		}, []validationResult{{
+			FileName:       "logo.png",
+			OriginalPath:   "b/logo.png",
+			OriginalSHA:    hash,
+			NewSHA:         hash,
+			SourceSHA:      hash,
+			Match:          true,
+			NewCRC32:       "0a1b2c3d",
+			CaseCollisions: []string{"LOGO.png"},
+			Aliases:        []aliasResult{{FileName: "logo-copy.png", OriginalPath: "c/logo-copy.png"}},
+		}}, []outputProblem{{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"}},
+			[]inputProblem{{Path: "a/logo.png", Problem: "duplicate is larger than file \"logo.png\" kept instead"}},
+			Options{VerifyInput: true, RepackageOptions: repackage.Options{KeepDepth: 2, NamePolicy: repackage.NamePolicyPortable}}, time.Now())
+		report.Manifest = &manifestResult{FileName: "MANIFEST.json", SHA: hash, Size: 120}
+
+		jsonData, err := json.Marshal(report)
+		require.NoError(t, err)
+		var document any
+		require.NoError(t, json.Unmarshal(jsonData, &document))
+
+		assertMatchesSchema(t, schema, schema, document, "$")
+	})
+}
+
+// assertMatchesSchema checks a decoded JSON document against the subset of JSON Schema used by
+// the report schema: references, types, required and known properties, enums, constants and patterns.
+func assertMatchesSchema(t *testing.T, root, schema map[string]any, value any, location string) {
+	if reference, ok := schema["$ref"].(string); ok {
+		definition := root
+		for _, key := range strings.Split(strings.TrimPrefix(reference, "#/"), "/") {
+			definition = definition[key].(map[string]any)
+		}
+		assertMatchesSchema(t, root, definition, value, location)
+		return
+	}
+
+	if constant, ok := schema["const"]; ok {
+		assert.Equal(t, constant, value, "%s should be constant", location)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		assert.Contains(t, enum, value, "%s should be one of the enumerated values", location)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !assert.True(t, ok, "%s should be an object", location) {
+			return
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, required := range schema["required"].([]any) {
+			assert.Contains(t, object, required, "%s should have property %s", location, required)
+		}
+		for key, propertyValue := range object {
+			propertySchema, known := properties[key].(map[string]any)
+			if assert.True(t, known, "%s has a property %s missing from the schema", location, key) {
+				assertMatchesSchema(t, root, propertySchema, propertyValue, location+"."+key)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !assert.True(t, ok, "%s should be an array", location) {
+			return
+		}
+		for index, item := range items {
+			assertMatchesSchema(t, root, schema["items"].(map[string]any), item, location+"["+strconv.Itoa(index)+"]")
+		}
+	case "string":
+		text, ok := value.(string)
+		if assert.True(t, ok, "%s should be a string", location) {
+			if pattern, hasPattern := schema["pattern"].(string); hasPattern {
+				assert.Regexp(t, regexp.MustCompile(pattern), text, "%s should match its pattern", location)
+			}
+		}
+	case "integer":
+		number, ok := value.(float64)
+		assert.True(t, ok && number == float64(int64(number)), "%s should be an integer", location)
+	case "boolean":
+		_, ok := value.(bool)
+		assert.True(t, ok, "%s should be a boolean", location)
+	}
+}
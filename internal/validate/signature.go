@@ -0,0 +1,95 @@
+package validate
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// signatureExtension is appended to the path of a report to name its signature.
+const signatureExtension = ".sig"
+
+// SignaturePath returns the path of the signature of a report, written next to it.
+func SignaturePath(reportPath string) string {
+	return reportPath + signatureExtension
+}
+
+// ReadSigningKey reads an ed25519 private key from a PEM file holding a PKCS #8 "PRIVATE KEY"
+// block, as written by "openssl genpkey -algorithm ed25519".
+func ReadSigningKey(keyPath string) (ed25519.PrivateKey, error) {
+	der, err := readPEMBlock(keyPath, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %w", keyPath, err)
+	}
+	signingKey, isEd25519 := key.(ed25519.PrivateKey)
+	if !isEd25519 {
+		return nil, fmt.Errorf("invalid private key in %s: expected an ed25519 key, got %T", keyPath, key)
+	}
+
+	return signingKey, nil
+}
+
+// ReadPublicKey reads an ed25519 public key from a PEM file holding a PKIX "PUBLIC KEY" block,
+// as written by "openssl pkey -pubout".
+func ReadPublicKey(keyPath string) (ed25519.PublicKey, error) {
+	der, err := readPEMBlock(keyPath, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %w", keyPath, err)
+	}
+	publicKey, isEd25519 := key.(ed25519.PublicKey)
+	if !isEd25519 {
+		return nil, fmt.Errorf("invalid public key in %s: expected an ed25519 key, got %T", keyPath, key)
+	}
+
+	return publicKey, nil
+}
+
+// readPEMBlock returns the content of the first PEM block of a file, which must be of the given type.
+func readPEMBlock(keyPath, blockType string) ([]byte, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("invalid key file %s: no PEM block found", keyPath)
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("invalid key file %s: expected a %q PEM block, got %q", keyPath, blockType, block.Type)
+	}
+
+	return block.Bytes, nil
+}
+
+// writeSignature signs the content of a report and writes the raw ed25519 signature next to it,
+// so that it can also be checked with "openssl pkeyutl -verify -rawin".
+func writeSignature(reportPath string, content []byte, signingKey ed25519.PrivateKey) error {
+	signature := ed25519.Sign(signingKey, content)
+	if err := os.WriteFile(SignaturePath(reportPath), signature, 0o644); err != nil {
+		return fmt.Errorf("failed to write validation report signature: %w", err)
+	}
+	return nil
+}
+
+// verifySignature reports whether the signature stored next to a report was made over its
+// content with the private key matching publicKey.
+func verifySignature(reportPath string, content []byte, publicKey ed25519.PublicKey) (bool, error) {
+	signature, err := os.ReadFile(SignaturePath(reportPath))
+	if err != nil {
+		return false, fmt.Errorf("failed to read validation report signature: %w", err)
+	}
+	return len(signature) == ed25519.SignatureSize && ed25519.Verify(publicKey, content, signature), nil
+}
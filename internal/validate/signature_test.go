@@ -0,0 +1,117 @@
+package validate
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSigningKey(t *testing.T) {
+	tempDir := t.TempDir()
+	_, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	t.Run("Returns error when the key file cannot be read", func(t *testing.T) {
+		key, err := ReadSigningKey(filepath.Join(tempDir, "missing.pem"))
+
+		assert.Error(t, err)
+		assert.Nil(t, key)
+		assert.Contains(t, err.Error(), "failed to read key file")
+	})
+
+	t.Run("Returns error when the key file isn't PEM", func(t *testing.T) {
+		keyPath := filepath.Join(tempDir, "key.txt")
+		require.NoError(t, os.WriteFile(keyPath, []byte("not a key"), 0o600))
+
+		key, err := ReadSigningKey(keyPath)
+
+		assert.Error(t, err)
+		assert.Nil(t, key)
+		assert.Contains(t, err.Error(), "no PEM block found")
+	})
+
+	t.Run("Returns error when given a public key", func(t *testing.T) {
+		keyPath := writePublicKey(t, tempDir, signingKey.Public().(ed25519.PublicKey))
+
+		key, err := ReadSigningKey(keyPath)
+
+		assert.Error(t, err)
+		assert.Nil(t, key)
+		assert.Contains(t, err.Error(), `expected a "PRIVATE KEY" PEM block, got "PUBLIC KEY"`)
+	})
+
+	t.Run("Successfully reads a PKCS #8 key", func(t *testing.T) {
+		keyPath := writeSigningKey(t, tempDir, signingKey)
+
+		key, err := ReadSigningKey(keyPath)
+
+		assert.NoError(t, err)
+		assert.Equal(t, signingKey, key)
+	})
+}
+
+func TestReadPublicKey(t *testing.T) {
+	tempDir := t.TempDir()
+	publicKey, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	t.Run("Returns error when given a private key", func(t *testing.T) {
+		keyPath := writeSigningKey(t, tempDir, signingKey)
+
+		key, err := ReadPublicKey(keyPath)
+
+		assert.Error(t, err)
+		assert.Nil(t, key)
+		assert.Contains(t, err.Error(), `expected a "PUBLIC KEY" PEM block, got "PRIVATE KEY"`)
+	})
+
+	t.Run("Returns error when the key is malformed", func(t *testing.T) {
+		keyPath := filepath.Join(tempDir, "malformed.pem")
+		require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}), 0o644))
+
+		key, err := ReadPublicKey(keyPath)
+
+		assert.Error(t, err)
+		assert.Nil(t, key)
+		assert.Contains(t, err.Error(), "invalid public key")
+	})
+
+	t.Run("Successfully reads a PKIX key", func(t *testing.T) {
+		keyPath := writePublicKey(t, tempDir, publicKey)
+
+		key, err := ReadPublicKey(keyPath)
+
+		assert.NoError(t, err)
+		assert.Equal(t, publicKey, key)
+	})
+}
+
+func TestSignaturePath(t *testing.T) {
+	t.Run("Successfully names the signature after the report", func(t *testing.T) {
+		assert.Equal(t, filepath.Join("out", "output_validation.json.sig"), SignaturePath(filepath.Join("out", "output_validation.json")))
+	})
+}
+
+// writeSigningKey writes a private key as a PKCS #8 PEM file and returns its path.
+func writeSigningKey(t *testing.T, dir string, signingKey ed25519.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return keyPath
+}
+
+// writePublicKey writes a public key as a PKIX PEM file and returns its path.
+func writePublicKey(t *testing.T, dir string, publicKey ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "pub.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+	return keyPath
+}
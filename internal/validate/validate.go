@@ -2,12 +2,18 @@ package validate
 
 import (
 	"archive/zip"
-	"encoding/hex"
-	"encoding/json"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/yash15112001/rezip/internal/repackage"
 )
@@ -18,27 +24,162 @@ type validationResult struct {
 	OriginalPath string `json:"original_path"`
 	OriginalSHA  string `json:"original_sha"`
 	NewSHA       string `json:"new_sha"`
+	OriginalSize int64  `json:"original_size"`
+	NewSize      int64  `json:"new_size"`
 	Match        bool   `json:"match"`
+
+	// NewCRC32 is the CRC-32 of the output entry, in hexadecimal, so that a later reader of the
+	// report can tell whether the entry was rewritten since. It is empty when the entry does not
+	// match its CRC-32.
+	NewCRC32 string `json:"new_crc32,omitempty"`
+
+	// SourceSHA is the checksum of the input entry at OriginalPath, read again from the input
+	// archive. It is only set when Options.VerifyInput is set.
+	SourceSHA string `json:"source_sha,omitempty"`
+
+	// CaseCollisions lists the other output names that only differ from this one by letter case.
+	CaseCollisions []string `json:"case_collisions,omitempty"`
+
+	// Aliases lists the files dropped because they have the same content as this one.
+	Aliases []aliasResult `json:"aliases,omitempty"`
+}
+
+// Kinds of problems found in the entries of the output ZIP.
+const (
+	outputProblemDuplicateName = "duplicate_name"
+	outputProblemUnexpected    = "unexpected"
+	outputProblemCRCMismatch   = "crc_mismatch"
+	outputProblemSizeMismatch  = "size_mismatch"
+)
+
+// outputProblem describes an entry of the output ZIP that is not consistent with the repackaging
+// result or with its own header.
+type outputProblem struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Problem string `json:"problem"`
+}
+
+// aliasResult represents a file replaced by an output file with identical content.
+type aliasResult struct {
+	FileName     string `json:"file_name"`
+	OriginalPath string `json:"original_path"`
+}
+
+// Options controls how an output ZIP is validated.
+type Options struct {
+	// HashCache caches the checksums of the output entries. It can be shared with repackaging
+	// to inspect the statistics of the whole run. A new cache is used when it is nil.
+	HashCache *repackage.HashCache
+
+	// InputZipPath is the path of the repackaged archive, recorded in the report metadata.
+	InputZipPath string
+
+	// MergedZipPaths are the archives merged with the input archive, recorded in the report
+	// metadata and reopened along with it by VerifyInput.
+	MergedZipPaths []string
+
+	// StartedAt is the start time of the run recorded in the report metadata. The start of the
+	// validation is used when it is zero.
+	StartedAt time.Time
+
+	// RepackageOptions are the options of the run, recorded in the report metadata.
+	RepackageOptions repackage.Options
+
+	// ReportPath is the path of the report file, or StdoutReportPath to write the report to the
+	// standard output. Defaults to "<output>_validation" next to the output ZIP, with the
+	// extension of the report format.
+	ReportPath string
+
+	// ReportFormat selects the format of the report. Defaults to ReportJSON.
+	ReportFormat ReportFormat
+
+	// VerifyInput reopens the input archives at InputZipPath and MergedZipPaths to check the output files against
+	// their source entries and every dropped entry against the deduplication rules, instead of
+	// only trusting the checksums computed while repackaging.
+	VerifyInput bool
+
+	// SigningKey signs the report, whose ed25519 signature is written next to it at
+	// SignaturePath. The report is not signed when it is nil. It requires a report file.
+	SigningKey ed25519.PrivateKey
+
+	// Logger receives an event for every mismatched file and every output or input problem, and
+	// the outcome of the validation. Nothing is logged when it is nil.
+	Logger *slog.Logger
 }
 
-// Run validates an output ZIP by comparing file hashes with the expected values
-// and writes a validation report as JSON.
-func Run(outputZipPath string, expectedFiles map[string]repackage.FileInfo) (bool, error) {
+// Run validates an output ZIP by comparing file hashes with the values expected by the
+// repackaging result and writes a validation report in the selected format. Cancelling the
+// context stops the validation between entries with the context error.
+func Run(ctx context.Context, outputZipPath string, result *repackage.Result, options Options) (bool, error) {
+	startedAt := options.StartedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
 	zipReader, actualFiles, err := readOutputZip(outputZipPath)
 	if err != nil {
 		return false, err
 	}
 	defer zipReader.Close()
 
-	results, allMatch, err := validateFileHashes(actualFiles, expectedFiles)
+	hashes := options.HashCache
+	if hashes == nil {
+		hashes = repackage.NewHashCache()
+	}
+
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	outputProblems, err := checkOutputEntries(ctx, zipReader.File, result.Files, result.ManifestName, hashes)
+	if err != nil {
+		return false, err
+	}
+
+	results, allMatch, err := validateFileHashes(actualFiles, result.Files, hashes)
 	if err != nil {
 		return false, err
 	}
+	allMatch = allMatch && len(outputProblems) == 0
 
-	if err := writeValidationReport(outputZipPath, results); err != nil {
+	var inputProblems []inputProblem
+	if options.VerifyInput {
+		inputProblems, err = verifyInput(ctx, append([]string{options.InputZipPath}, options.MergedZipPaths...), result, results, hashes)
+		if err != nil {
+			return false, err
+		}
+		allMatch = allMatch && len(inputProblems) == 0
+	}
+
+	for _, fileResult := range results {
+		if !fileResult.Match {
+			logger.Warn("checksum mismatch", "name", fileResult.FileName, "path", fileResult.OriginalPath,
+				"expected", fileResult.OriginalSHA, "actual", fileResult.NewSHA)
+		}
+	}
+	for _, problem := range outputProblems {
+		logger.Warn("output problem", "name", problem.Name, "kind", problem.Kind, "problem", problem.Problem)
+	}
+	for _, problem := range inputProblems {
+		logger.Warn("input problem", "path", problem.Path, "problem", problem.Problem)
+	}
+
+	report := buildValidationReport(outputZipPath, result, results, outputProblems, inputProblems, options, startedAt)
+	if manifestFile, exists := actualFiles[result.ManifestName]; exists && result.ManifestName != "" {
+		report.Manifest, err = buildManifestResult(manifestFile, options.RepackageOptions.HashAlgorithm, hashes)
+		if err != nil {
+			return false, err
+		}
+	}
+	if err := writeValidationReport(report, ReportPath(outputZipPath, options), options.ReportFormat, options.SigningKey); err != nil {
 		return false, err
 	}
 
+	logger.Info("validated output archive", "path", outputZipPath, "valid", allMatch, "files", len(results),
+		"report", ReportPath(outputZipPath, options))
+
 	return allMatch, nil
 }
 
@@ -56,8 +197,82 @@ func readOutputZip(outputZipPath string) (*zip.ReadCloser, map[string]*zip.File,
 	return zipReader, actualFiles, nil
 }
 
+// checkOutputEntries checks every entry of the output ZIP, in central directory order, for
+// names that appear more than once, names that are neither expected nor the embedded manifest,
+// and data whose CRC-32 or size disagrees with the entry header.
+func checkOutputEntries(ctx context.Context, files []*zip.File, expectedFiles map[string]repackage.FileInfo, manifestName string,
+	hashes *repackage.HashCache) ([]outputProblem, error) {
+	var problems []outputProblem
+
+	nameCounts := make(map[string]int, len(files))
+	for _, file := range files {
+		nameCounts[file.Name]++
+	}
+
+	reportedNames := make(map[string]bool, len(files))
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !reportedNames[file.Name] {
+			reportedNames[file.Name] = true
+
+			if count := nameCounts[file.Name]; count > 1 {
+				problems = append(problems, outputProblem{
+					Name:    file.Name,
+					Kind:    outputProblemDuplicateName,
+					Problem: fmt.Sprintf("the central directory lists %d entries with this name", count),
+				})
+			}
+
+			if _, isExpected := expectedFiles[file.Name]; !isExpected && (manifestName == "" || file.Name != manifestName) {
+				problems = append(problems, outputProblem{
+					Name:    file.Name,
+					Kind:    outputProblemUnexpected,
+					Problem: "entry is not part of the repackaging result",
+				})
+			}
+		}
+
+		// The checksum is computed with the algorithm of the file, to be reused by validateFileHashes.
+		actualSize, _, err := hashes.Measure(file, expectedFiles[file.Name].HashAlgorithm)
+		if errors.Is(err, zip.ErrChecksum) {
+			problems = append(problems, outputProblem{
+				Name:    file.Name,
+				Kind:    outputProblemCRCMismatch,
+				Problem: fmt.Sprintf("data does not match the CRC-32 %08x declared in the header", file.CRC32),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output file '%s': %w", file.Name, err)
+		}
+
+		if declaredSize := int64(file.UncompressedSize64); actualSize != declaredSize {
+			problems = append(problems, outputProblem{
+				Name:    file.Name,
+				Kind:    outputProblemSizeMismatch,
+				Problem: fmt.Sprintf("header declares %d bytes but the entry contains %d bytes", declaredSize, actualSize),
+			})
+		} else if file.Method == zip.Store && file.CompressedSize64 != file.UncompressedSize64 {
+			problems = append(problems, outputProblem{
+				Name: file.Name,
+				Kind: outputProblemSizeMismatch,
+				Problem: fmt.Sprintf("stored entry declares a compressed size of %d bytes and an uncompressed size of %d bytes",
+					file.CompressedSize64, file.UncompressedSize64),
+			})
+		}
+	}
+
+	return problems, nil
+}
+
 // validateFileHashes compares the hash of each file in the output ZIP with its expected hash.
-func validateFileHashes(actualFiles map[string]*zip.File, expectedFiles map[string]repackage.FileInfo) ([]validationResult, bool, error) {
+// Files whose data does not match their CRC-32 are reported as mismatches without a hash, as
+// checkOutputEntries reports the corruption itself. The results are sorted by file name.
+func validateFileHashes(actualFiles map[string]*zip.File, expectedFiles map[string]repackage.FileInfo,
+	hashes *repackage.HashCache) ([]validationResult, bool, error) {
 	results := make([]validationResult, 0, len(expectedFiles))
 	allMatch := true
 
@@ -67,49 +282,104 @@ func validateFileHashes(actualFiles map[string]*zip.File, expectedFiles map[stri
 			return nil, false, fmt.Errorf("missing file in output zip: %s", name)
 		}
 
-		actualHash, err := repackage.HashOf(actualFile)
-		if err != nil {
+		// Sizes are measured, as the header of a corrupted entry cannot be trusted.
+		actualSize, actualHash, err := hashes.Measure(actualFile, expectedInfo.HashAlgorithm)
+		isCorrupted := errors.Is(err, zip.ErrChecksum)
+		if err != nil && !isCorrupted {
 			return nil, false, fmt.Errorf("failed to compute hash for output file '%s': %w", name, err)
 		}
 
-		expectedHashHex := hex.EncodeToString(expectedInfo.Hash[:])
-		actualHashHex := hex.EncodeToString(actualHash[:])
-		match := expectedHashHex == actualHashHex
+		expectedHashHex := expectedInfo.Hash.String()
+		actualHashHex := actualHash.String()
+		actualCRC32 := fmt.Sprintf("%08x", actualFile.CRC32)
+		if isCorrupted {
+			actualHashHex, actualCRC32, actualSize = "", "", int64(actualFile.UncompressedSize64)
+		}
+		match := !isCorrupted && expectedHashHex == actualHashHex
+
+		var aliases []aliasResult
+		for _, alias := range expectedInfo.Aliases {
+			aliases = append(aliases, aliasResult{
+				FileName:     alias.Name,
+				OriginalPath: alias.OriginalPath,
+			})
+		}
 
 		results = append(results, validationResult{
-			FileName:     name,
-			OriginalPath: expectedInfo.OriginalPath,
-			OriginalSHA:  expectedHashHex,
-			NewSHA:       actualHashHex,
-			Match:        match,
+			FileName:       name,
+			OriginalPath:   expectedInfo.OriginalPath,
+			OriginalSHA:    expectedHashHex,
+			NewSHA:         actualHashHex,
+			OriginalSize:   expectedInfo.Size,
+			NewSize:        actualSize,
+			Match:          match,
+			NewCRC32:       actualCRC32,
+			CaseCollisions: expectedInfo.CaseCollisions,
+			Aliases:        aliases,
 		})
 
 		allMatch = allMatch && match
 	}
 
+	slices.SortFunc(results, func(a, b validationResult) int {
+		return strings.Compare(a.FileName, b.FileName)
+	})
+
 	return results, allMatch, nil
 }
 
-// writeValidationReport writes validation results to a JSON file.
-func writeValidationReport(outputZipPath string, results []validationResult) error {
+// ReportPath returns the path where the report of an output ZIP is written with the given options.
+func ReportPath(outputZipPath string, options Options) string {
+	if options.ReportPath != "" {
+		return options.ReportPath
+	}
+
 	outputDir := filepath.Dir(outputZipPath)
 	baseName := strings.TrimSuffix(filepath.Base(outputZipPath), filepath.Ext(outputZipPath))
-	reportPath := filepath.Join(outputDir, baseName+"_validation.json")
+	return filepath.Join(outputDir, baseName+"_validation"+options.ReportFormat.extension())
+}
 
-	reportFile, err := os.Create(reportPath)
+// buildManifestResult describes the manifest embedded in the output ZIP with its checksum.
+// Corrupted manifests are left out, as checkOutputEntries reports them.
+func buildManifestResult(manifestFile *zip.File, algorithm repackage.HashAlgorithm, hashes *repackage.HashCache) (*manifestResult, error) {
+	size, hash, err := hashes.Measure(manifestFile, algorithm)
+	if errors.Is(err, zip.ErrChecksum) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create validation report file: %w", err)
+		return nil, fmt.Errorf("failed to compute hash for manifest '%s': %w", manifestFile.Name, err)
+	}
+
+	return &manifestResult{FileName: manifestFile.Name, SHA: hash.String(), Size: size}, nil
+}
+
+// writeValidationReport writes the validation report to a file, or to the standard output
+// when the path is StdoutReportPath. Report files are signed with signingKey, unless it is nil.
+func writeValidationReport(report validationReport, reportPath string, format ReportFormat, signingKey ed25519.PrivateKey) error {
+	if reportPath == StdoutReportPath {
+		if err := encodeReport(os.Stdout, report, format); err != nil {
+			return fmt.Errorf("failed to write validation report: %w", err)
+		}
+		return nil
 	}
-	defer reportFile.Close()
 
-	jsonData, err := json.MarshalIndent(results, "", "  ")
+	var content bytes.Buffer
+	if err := encodeReport(&content, report, format); err != nil {
+		return fmt.Errorf("failed to write validation report: %w", err)
+	}
+
+	reportFile, err := os.Create(reportPath)
 	if err != nil {
-		return fmt.Errorf("error generating JSON report: %w", err)
+		return fmt.Errorf("failed to create validation report file: %w", err)
 	}
+	defer reportFile.Close()
 
-	if _, err := reportFile.Write(jsonData); err != nil {
+	if _, err := reportFile.Write(content.Bytes()); err != nil {
 		return fmt.Errorf("failed to write validation report: %w", err)
 	}
 
+	if signingKey != nil {
+		return writeSignature(reportPath, content.Bytes(), signingKey)
+	}
 	return nil
 }
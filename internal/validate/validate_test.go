@@ -3,9 +3,17 @@ package validate
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"hash/crc32"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,7 +26,7 @@ func TestRun(t *testing.T) {
 		tempDir := t.TempDir()
 		nonexistentPath := filepath.Join(tempDir, "nonexistent.zip")
 
-		allMatch, err := Run(nonexistentPath, map[string]repackage.FileInfo{})
+		allMatch, err := Run(context.Background(), nonexistentPath, &repackage.Result{}, Options{})
 
 		assert.Error(t, err)
 		assert.False(t, allMatch)
@@ -38,11 +46,11 @@ func TestRun(t *testing.T) {
 		expected := map[string]repackage.FileInfo{
 			"missing-file.txt": {
 				OriginalPath: "original/missing-file.txt",
-				Hash:         [32]byte{},
+				Hash:         "",
 			},
 		}
 
-		allMatch, err := Run(zipPath, expected)
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{})
 
 		assert.Error(t, err)
 		assert.False(t, allMatch)
@@ -75,7 +83,7 @@ func TestRun(t *testing.T) {
 		err = os.Chmod(readOnlyDir, 0555)
 		require.NoError(t, err)
 
-		allMatch, err := Run(zipPath, expected)
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{})
 
 		assert.Error(t, err)
 		assert.False(t, allMatch)
@@ -99,7 +107,7 @@ func TestRun(t *testing.T) {
 		// Build expected files map with correct hashes.
 		expected := buildExpectedFilesMap(t, zipPath)
 
-		_, err := Run(zipPath, expected)
+		_, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{})
 
 		assert.NoError(t, err, "Validation process should complete without errors")
 
@@ -110,10 +118,165 @@ func TestRun(t *testing.T) {
 		reportData, err := os.ReadFile(reportPath)
 		assert.NoError(t, err)
 
-		var results []validationResult
-		err = json.Unmarshal(reportData, &results)
+		var report validationReport
+		err = json.Unmarshal(reportData, &report)
 		assert.NoError(t, err, "Report should contain valid JSON")
-		assert.NotEmpty(t, results, "Report should contain validation results")
+		assert.NotEmpty(t, report.Files, "Report should contain validation results")
+		assert.Equal(t, reportVersion, report.Version)
+		assert.Equal(t, zipPath, report.Metadata.OutputPath)
+		assert.True(t, report.Summary.Valid)
+	})
+
+	t.Run("Successfully writes the report to the given path and format", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1"})
+		expected := buildExpectedFilesMap(t, zipPath)
+		reportPath := filepath.Join(tempDir, "reports", "validation.csv")
+		require.NoError(t, os.Mkdir(filepath.Dir(reportPath), 0755))
+
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{ReportPath: reportPath, ReportFormat: ReportCSV})
+
+		assert.NoError(t, err)
+		assert.True(t, allMatch)
+		assert.NoFileExists(t, filepath.Join(tempDir, "output_validation.json"))
+
+		reportData, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(reportData), "file_name,original_path,"))
+	})
+
+	t.Run("Successfully reports input problems when verifying the input", func(t *testing.T) {
+		tempDir := t.TempDir()
+		inputPath := filepath.Join(tempDir, "input.zip")
+		makeTestZip(t, inputPath, map[string]string{"dir/file1.txt": "content1", "dir/file2.txt": "content2"})
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1"})
+
+		expected := buildExpectedFilesMap(t, zipPath)
+		fileInfo := expected["file1.txt"]
+		fileInfo.OriginalPath = "dir/file1.txt"
+		expected["file1.txt"] = fileInfo
+
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{InputZipPath: inputPath, VerifyInput: true})
+
+		assert.NoError(t, err)
+		assert.False(t, allMatch, "Unaccounted input entries should fail the validation")
+
+		reportData, err := os.ReadFile(filepath.Join(tempDir, "output_validation.json"))
+		require.NoError(t, err)
+		var report validationReport
+		require.NoError(t, json.Unmarshal(reportData, &report))
+		assert.True(t, report.Metadata.InputVerified)
+		assert.False(t, report.Summary.Valid)
+		assert.Equal(t, []inputProblem{
+			{Path: "dir/file2.txt", Problem: "input entry is neither in the output nor reported as skipped"},
+		}, report.InputProblems)
+		assert.Equal(t, report.Files[0].NewSHA, report.Files[0].SourceSHA)
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		allMatch, err := Run(ctx, zipPath, &repackage.Result{Files: buildExpectedFilesMap(t, zipPath)}, Options{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, allMatch)
+	})
+
+	t.Run("Successfully fails the validation of an output with an unexpected entry", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1", "extra.txt": "extra"})
+		expected := buildExpectedFilesMap(t, zipPath)
+		delete(expected, "extra.txt")
+
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{})
+
+		assert.NoError(t, err)
+		assert.False(t, allMatch)
+
+		reportData, err := os.ReadFile(filepath.Join(tempDir, "output_validation.json"))
+		require.NoError(t, err)
+		var report validationReport
+		require.NoError(t, json.Unmarshal(reportData, &report))
+		assert.False(t, report.Summary.Valid)
+		assert.Equal(t, []outputProblem{
+			{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"},
+		}, report.OutputProblems)
+	})
+
+	t.Run("Successfully logs problems and the outcome of the validation", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1", "extra.txt": "extra"})
+		expected := buildExpectedFilesMap(t, zipPath)
+		delete(expected, "extra.txt")
+
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+				if attr.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return attr
+			},
+		}))
+
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{Logger: logger})
+
+		assert.NoError(t, err)
+		assert.False(t, allMatch)
+		assert.Contains(t, logs.String(),
+			`level=WARN msg="output problem" name=extra.txt kind=unexpected problem="entry is not part of the repackaging result"`)
+		assert.Contains(t, logs.String(), "level=INFO msg=\"validated output archive\" path="+zipPath+" valid=false files=1")
+	})
+
+	t.Run("Successfully hashes output entries through the given cache", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1", "file2.txt": "content2"})
+		expected := buildExpectedFilesMap(t, zipPath)
+
+		hashCache := repackage.NewHashCache()
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected}, Options{HashCache: hashCache})
+
+		assert.NoError(t, err)
+		assert.True(t, allMatch)
+		assert.Equal(t, repackage.HashCacheStats{Hits: 2, Misses: 2}, hashCache.Stats())
+	})
+
+	t.Run("Successfully signs the report and records the embedded manifest", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1", "MANIFEST.json": "{}"})
+		expected := buildExpectedFilesMap(t, zipPath)
+		delete(expected, "MANIFEST.json")
+		publicKey, signingKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		allMatch, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected, ManifestName: "MANIFEST.json"},
+			Options{SigningKey: signingKey})
+
+		assert.NoError(t, err)
+		assert.True(t, allMatch)
+
+		reportPath := filepath.Join(tempDir, "output_validation.json")
+		reportData, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		signature, err := os.ReadFile(SignaturePath(reportPath))
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(publicKey, reportData, signature))
+
+		var report validationReport
+		require.NoError(t, json.Unmarshal(reportData, &report))
+		manifestHash := sha256.Sum256([]byte("{}"))
+		assert.Equal(t, &manifestResult{FileName: "MANIFEST.json", SHA: hex.EncodeToString(manifestHash[:]), Size: 2}, report.Manifest)
 	})
 }
 
@@ -174,10 +337,10 @@ func TestValidateFileHashes(t *testing.T) {
 		expected := buildExpectedFilesMap(t, zipPath)
 		expected["missing.txt"] = repackage.FileInfo{
 			OriginalPath: "missing.txt",
-			Hash:         [32]byte{},
+			Hash:         "",
 		}
 
-		results, allMatch, err := validateFileHashes(actualFiles, expected)
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
 
 		assert.Error(t, err)
 		assert.False(t, allMatch)
@@ -190,12 +353,12 @@ func TestValidateFileHashes(t *testing.T) {
 		corruptedFile := makeCorruptedZipFile(t, "bad.txt", []byte("hello world"))
 
 		actualFiles := map[string]*zip.File{"bad.txt": corruptedFile}
-		var dummyHash [32]byte
+		var dummyHash repackage.Digest
 		expected := map[string]repackage.FileInfo{
 			"bad.txt": {Hash: dummyHash, OriginalPath: "irrelevant"},
 		}
 
-		results, allMatch, err := validateFileHashes(actualFiles, expected)
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(),
@@ -224,17 +387,81 @@ func TestValidateFileHashes(t *testing.T) {
 		}
 		expected := buildExpectedFilesMap(t, zipPath)
 
-		results, allMatch, err := validateFileHashes(actualFiles, expected)
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
 
 		assert.NoError(t, err)
 		assert.True(t, allMatch)
-		assert.Len(t, results, 2)
+		require.Len(t, results, 2)
+		assert.Equal(t, "file1.txt", results[0].FileName, "Results should be sorted by file name")
+		assert.Equal(t, "file2.txt", results[1].FileName, "Results should be sorted by file name")
 
 		for _, result := range results {
 			assert.True(t, result.Match)
+			assert.Equal(t, int64(len("content1")), result.OriginalSize)
+			assert.Equal(t, int64(len("content1")), result.NewSize)
+		}
+	})
+
+	t.Run("Successfully reports case collisions of expected files", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+
+		entries := map[string]string{
+			"README.md": "docs",
+			"readme.md": "source readme",
+		}
+		makeTestZip(t, zipPath, entries)
+
+		zipReader, err := zip.OpenReader(zipPath)
+		require.NoError(t, err)
+		defer zipReader.Close()
+
+		actualFiles := make(map[string]*zip.File)
+		for _, file := range zipReader.File {
+			actualFiles[file.Name] = file
+		}
+
+		expected := buildExpectedFilesMap(t, zipPath)
+		upperInfo := expected["README.md"]
+		upperInfo.CaseCollisions = []string{"readme.md"}
+		expected["README.md"] = upperInfo
+
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.True(t, allMatch)
+		for _, result := range results {
+			if result.FileName == "README.md" {
+				assert.Equal(t, []string{"readme.md"}, result.CaseCollisions)
+			} else {
+				assert.Empty(t, result.CaseCollisions)
+			}
 		}
 	})
 
+	t.Run("Successfully reports aliases of expected files", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "output.zip")
+		makeTestZip(t, zipPath, map[string]string{"logo.png": "png bytes"})
+
+		zipReader, err := zip.OpenReader(zipPath)
+		require.NoError(t, err)
+		defer zipReader.Close()
+
+		actualFiles := map[string]*zip.File{"logo.png": zipReader.File[0]}
+		expected := buildExpectedFilesMap(t, zipPath)
+		logoInfo := expected["logo.png"]
+		logoInfo.Aliases = []repackage.Alias{{Name: "logo-copy.png", OriginalPath: "img/logo-copy.png"}}
+		expected["logo.png"] = logoInfo
+
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.True(t, allMatch)
+		require.Len(t, results, 1)
+		assert.Equal(t, []aliasResult{{FileName: "logo-copy.png", OriginalPath: "img/logo-copy.png"}}, results[0].Aliases)
+	})
+
 	t.Run("Successfully returns false with mismatched hashes", func(t *testing.T) {
 		tempDir := t.TempDir()
 		zipPath := filepath.Join(tempDir, "output.zip")
@@ -261,12 +488,91 @@ func TestValidateFileHashes(t *testing.T) {
 			Hash:         corruptHash(expected["file1.txt"].Hash),
 		}
 
-		results, allMatch, err := validateFileHashes(actualFiles, expected)
+		results, allMatch, err := validateFileHashes(actualFiles, expected, repackage.NewHashCache())
 
 		assert.NoError(t, err)
 		assert.False(t, allMatch)
 		assert.Len(t, results, 2)
 	})
+
+	t.Run("Successfully reports a mismatch for an entry disagreeing with its CRC-32", func(t *testing.T) {
+		files := makeStoredZipFiles(t, storedTestEntry{name: "crc.txt", content: "crc", crc32: 1})
+		expected := map[string]repackage.FileInfo{"crc.txt": {OriginalPath: "crc.txt", Size: 3}}
+
+		results, allMatch, err := validateFileHashes(map[string]*zip.File{"crc.txt": files[0]}, expected, repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.False(t, allMatch)
+		require.Len(t, results, 1)
+		assert.Empty(t, results[0].NewSHA)
+		assert.Equal(t, int64(3), results[0].NewSize)
+	})
+}
+
+func TestCheckOutputEntries(t *testing.T) {
+	// expectedFiles builds the repackaging result of entries stored with correct headers.
+	expectedFiles := func(names ...string) map[string]repackage.FileInfo {
+		files := make(map[string]repackage.FileInfo, len(names))
+		for _, name := range names {
+			files[name] = repackage.FileInfo{OriginalPath: "dir/" + name}
+		}
+		return files
+	}
+
+	t.Run("Returns error when an output entry can't be read", func(t *testing.T) {
+		corruptedFile := makeCorruptedZipFile(t, "bad.txt", []byte("hello world"))
+
+		problems, err := checkOutputEntries(context.Background(), []*zip.File{corruptedFile}, expectedFiles("bad.txt"), "", repackage.NewHashCache())
+
+		assert.Error(t, err)
+		assert.Nil(t, problems)
+		assert.Contains(t, err.Error(), "failed to read output file 'bad.txt'")
+	})
+
+	t.Run("Successfully accepts consistent entries and the embedded manifest", func(t *testing.T) {
+		files := makeStoredZipFiles(t,
+			storedTestEntry{name: "a.txt", content: "aaa"},
+			storedTestEntry{name: "MANIFEST.json", content: "{}"},
+		)
+
+		problems, err := checkOutputEntries(context.Background(), files, expectedFiles("a.txt"), "MANIFEST.json", repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("Successfully detects unexpected and duplicate entries", func(t *testing.T) {
+		files := makeStoredZipFiles(t,
+			storedTestEntry{name: "a.txt", content: "aaa"},
+			storedTestEntry{name: "extra.txt", content: "extra"},
+			storedTestEntry{name: "a.txt", content: "aaa"},
+			storedTestEntry{name: "MANIFEST.json", content: "{}"},
+		)
+
+		problems, err := checkOutputEntries(context.Background(), files, expectedFiles("a.txt"), "", repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []outputProblem{
+			{Name: "a.txt", Kind: outputProblemDuplicateName, Problem: "the central directory lists 2 entries with this name"},
+			{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"},
+			{Name: "MANIFEST.json", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"},
+		}, problems)
+	})
+
+	t.Run("Successfully detects entries disagreeing with their header", func(t *testing.T) {
+		files := makeStoredZipFiles(t,
+			storedTestEntry{name: "crc.txt", content: "crc", crc32: 1},
+			storedTestEntry{name: "larger.txt", content: "larger", declaredSize: 3},
+		)
+
+		problems, err := checkOutputEntries(context.Background(), files, expectedFiles("crc.txt", "larger.txt"), "", repackage.NewHashCache())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []outputProblem{
+			{Name: "crc.txt", Kind: outputProblemCRCMismatch, Problem: "data does not match the CRC-32 00000001 declared in the header"},
+			{Name: "larger.txt", Kind: outputProblemSizeMismatch, Problem: "header declares 3 bytes but the entry contains 6 bytes"},
+		}, problems)
+	})
 }
 
 func TestWriteValidationReport(t *testing.T) {
@@ -277,13 +583,13 @@ func TestWriteValidationReport(t *testing.T) {
 		err := os.Mkdir(reportDir, 0755)
 		require.NoError(t, err)
 
-		zipPath := filepath.Join(reportDir, "output.zip")
+		reportPath := filepath.Join(reportDir, "output_validation.json")
 
 		// Make the directory read-only to cause file creation to fail.
 		err = os.Chmod(reportDir, 0555)
 		require.NoError(t, err)
 
-		err = writeValidationReport(zipPath, []validationResult{})
+		err = writeValidationReport(validationReport{}, reportPath, ReportJSON, nil)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to create validation report file")
@@ -291,7 +597,7 @@ func TestWriteValidationReport(t *testing.T) {
 
 	t.Run("Successfully writes validation report", func(t *testing.T) {
 		tempDir := t.TempDir()
-		zipPath := filepath.Join(tempDir, "output.zip")
+		reportPath := filepath.Join(tempDir, "output_validation.json")
 
 		results := []validationResult{
 			{
@@ -310,13 +616,42 @@ func TestWriteValidationReport(t *testing.T) {
 			},
 		}
 
-		err := writeValidationReport(zipPath, results)
+		err := writeValidationReport(validationReport{Version: reportVersion, Files: results}, reportPath, ReportJSON, nil)
 
 		assert.NoError(t, err)
-
-		reportPath := filepath.Join(tempDir, "output_validation.json")
 		assert.FileExists(t, reportPath)
 	})
+
+	t.Run("Successfully writes validation report to the standard output", func(t *testing.T) {
+		reader, writer, err := os.Pipe()
+		require.NoError(t, err)
+		originalStdout := os.Stdout
+		os.Stdout = writer
+		defer func() { os.Stdout = originalStdout }()
+
+		err = writeValidationReport(validationReport{Version: reportVersion}, StdoutReportPath, ReportNDJSON, nil)
+		require.NoError(t, writer.Close())
+		output, readErr := io.ReadAll(reader)
+
+		assert.NoError(t, err)
+		require.NoError(t, readErr)
+		assert.True(t, bytes.HasPrefix(output, []byte(`{"record":"report","version":1,`)))
+	})
+}
+
+func TestReportPath(t *testing.T) {
+	t.Run("Successfully uses the given path", func(t *testing.T) {
+		assert.Equal(t, "/reports/run.xml", ReportPath("/out/output.zip", Options{ReportPath: "/reports/run.xml"}))
+		assert.Equal(t, StdoutReportPath, ReportPath("/out/output.zip", Options{ReportPath: StdoutReportPath}))
+	})
+
+	t.Run("Successfully defaults to a file next to the output zip", func(t *testing.T) {
+		assert.Equal(t, filepath.Join("out", "output_validation.json"), ReportPath(filepath.Join("out", "output.zip"), Options{}))
+		assert.Equal(t, filepath.Join("out", "output_validation.xml"),
+			ReportPath(filepath.Join("out", "output.zip"), Options{ReportFormat: ReportJUnit}))
+		assert.Equal(t, filepath.Join("out", "output_validation.md"),
+			ReportPath(filepath.Join("out", "output.zip"), Options{ReportFormat: ReportMarkdown}))
+	})
 }
 
 // Helper to create a zip file at path with given entries mapping name->content.
@@ -344,25 +679,68 @@ func buildExpectedFilesMap(t *testing.T, zipPath string) map[string]repackage.Fi
 	defer zipReader.Close()
 
 	for _, file := range zipReader.File {
-		hash, err := repackage.HashOf(file)
+		hash, err := repackage.HashOf(file, repackage.HashSHA256)
 		require.NoError(t, err, "Failed to hash ZIP entry")
 		expected[file.Name] = repackage.FileInfo{
 			OriginalPath: file.Name,
 			Hash:         hash,
+			Size:         int64(file.UncompressedSize64),
 		}
 	}
 
 	return expected
 }
 
-func corruptHash(original [32]byte) [32]byte {
-	corrupted := original
+func corruptHash(original repackage.Digest) repackage.Digest {
+	corrupted := []byte(original)
 
 	// Change a few bytes to create a different hash.
 	for i := 0; i < 5; i++ {
 		corrupted[i] = ^corrupted[i]
 	}
-	return corrupted
+	return repackage.Digest(corrupted)
+}
+
+// storedTestEntry describes an uncompressed entry written by makeStoredZipFiles. A non-zero crc32
+// or declaredSize replaces the correct value in the entry header.
+type storedTestEntry struct {
+	name         string
+	content      string
+	crc32        uint32
+	declaredSize uint64
+}
+
+// makeStoredZipFiles builds a ZIP in memory with the given entries, in order, and returns its files.
+func makeStoredZipFiles(t *testing.T, entries ...storedTestEntry) []*zip.File {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE([]byte(entry.content)),
+			CompressedSize64:   uint64(len(entry.content)),
+			UncompressedSize64: uint64(len(entry.content)),
+		}
+		if entry.crc32 != 0 {
+			header.CRC32 = entry.crc32
+		}
+		if entry.declaredSize != 0 {
+			header.UncompressedSize64 = entry.declaredSize
+		}
+
+		writer, err := zipWriter.CreateRaw(header)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	return zipReader.File
 }
 
 // makeCorruptedZipFile builds a one-entry ZIP in memory, then
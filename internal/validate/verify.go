@@ -0,0 +1,164 @@
+package validate
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+const (
+	// outputProblemMissing marks an output file listed by the report that the output ZIP lacks.
+	outputProblemMissing = "missing"
+
+	// reportProblemWeakHash marks a report whose checksums can be forged, so that its signature
+	// does not vouch for the entries.
+	reportProblemWeakHash = "weak_hash"
+)
+
+// Verification is the outcome of the verification of an output ZIP against its signed report.
+type Verification struct {
+	// Valid is true when the signature is valid, the report records a valid output and every
+	// entry of the output ZIP matches the report.
+	Valid bool `json:"valid"`
+
+	// SignatureValid is true when the report was signed with the private key of the public key.
+	SignatureValid bool `json:"signature_valid"`
+
+	// ReportValid is the validity recorded in the report when it was written.
+	ReportValid bool `json:"report_valid"`
+
+	HashAlgorithm repackage.HashAlgorithm `json:"hash_algorithm"`
+
+	// Files lists the files of the report, and the embedded manifest, found in the output ZIP,
+	// sorted by name.
+	Files []VerifiedFile `json:"files"`
+
+	// Problems lists the checksums of the report when they are not collision resistant, the files
+	// of the report missing from the output ZIP and the entries of the output ZIP that are
+	// unexpected, listed more than once or inconsistent with their header.
+	Problems []VerifyProblem `json:"problems"`
+}
+
+// VerifiedFile is the outcome of the check of an output file against the checksum recorded by
+// the report.
+type VerifiedFile struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+
+	// Actual is the checksum of the entry. It is empty when its data does not match its CRC-32.
+	Actual string `json:"actual"`
+	Match  bool   `json:"match"`
+}
+
+// VerifyProblem describes an entry of the output ZIP that is not consistent with the report or
+// with its own header.
+type VerifyProblem struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Problem string `json:"problem"`
+}
+
+// Verify checks the ed25519 signature of a JSON validation report, written next to it at
+// SignaturePath, and that every entry of the output ZIP has the checksum recorded by the report.
+// Entries are hashed with the algorithm of the report, which must be collision resistant, and the
+// embedded manifest is checked when the report records it. Cancelling the context stops the verification between entries
+// with the context error.
+func Verify(ctx context.Context, outputZipPath, reportPath string, publicKey ed25519.PublicKey) (*Verification, error) {
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation report: %w", err)
+	}
+
+	signatureValid, err := verifySignature(reportPath, content, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	report, algorithm, err := parseReport(content)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedFiles := make(map[string]repackage.FileInfo, len(report.Files)+1)
+	expectedHashes := make(map[string]string, len(report.Files)+1)
+	for _, fileResult := range report.Files {
+		expectedFiles[fileResult.FileName] = repackage.FileInfo{HashAlgorithm: algorithm}
+		expectedHashes[fileResult.FileName] = fileResult.OriginalSHA
+	}
+	if report.Manifest != nil {
+		expectedFiles[report.Manifest.FileName] = repackage.FileInfo{HashAlgorithm: algorithm}
+		expectedHashes[report.Manifest.FileName] = report.Manifest.SHA
+	}
+
+	zipReader, actualFiles, err := readOutputZip(outputZipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zipReader.Close()
+
+	hashes := repackage.NewHashCache()
+
+	outputProblems, err := checkOutputEntries(ctx, zipReader.File, expectedFiles, "", hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &Verification{
+		SignatureValid: signatureValid,
+		ReportValid:    report.Summary.Valid,
+		HashAlgorithm:  algorithm,
+		Files:          make([]VerifiedFile, 0, len(expectedFiles)),
+		Problems:       make([]VerifyProblem, 0, len(outputProblems)),
+	}
+	if !algorithm.IsCollisionResistant() {
+		verification.Problems = append(verification.Problems, VerifyProblem{
+			Name:    filepath.Base(reportPath),
+			Kind:    reportProblemWeakHash,
+			Problem: fmt.Sprintf("%s checksums can be forged, so the signature does not cover the entries", algorithm),
+		})
+	}
+	for _, problem := range outputProblems {
+		verification.Problems = append(verification.Problems, VerifyProblem(problem))
+	}
+
+	allMatch := true
+	for _, name := range slices.Sorted(maps.Keys(expectedFiles)) {
+		actualFile, exists := actualFiles[name]
+		if !exists {
+			verification.Problems = append(verification.Problems, VerifyProblem{
+				Name:    name,
+				Kind:    outputProblemMissing,
+				Problem: "file listed by the report is missing from the output zip",
+			})
+			continue
+		}
+
+		// The checksums were computed by checkOutputEntries with the algorithm of the report.
+		actualHash, err := hashes.Sum(actualFile, algorithm)
+		isCorrupted := errors.Is(err, zip.ErrChecksum)
+		if err != nil && !isCorrupted {
+			return nil, fmt.Errorf("failed to compute hash for output file '%s': %w", name, err)
+		}
+
+		verifiedFile := VerifiedFile{Name: name, Expected: expectedHashes[name]}
+		if !isCorrupted {
+			verifiedFile.Actual = actualHash.String()
+		}
+		verifiedFile.Match = !isCorrupted && verifiedFile.Actual == verifiedFile.Expected
+		verification.Files = append(verification.Files, verifiedFile)
+
+		allMatch = allMatch && verifiedFile.Match
+	}
+
+	verification.Valid = signatureValid && verification.ReportValid && allMatch && len(verification.Problems) == 0
+
+	return verification, nil
+}
@@ -0,0 +1,170 @@
+package validate
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yash15112001/rezip/internal/repackage"
+)
+
+func TestVerify(t *testing.T) {
+	tempDir := t.TempDir()
+	publicKey, signingKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPublicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	zipPath := filepath.Join(tempDir, "output.zip")
+	makeTestZip(t, zipPath, map[string]string{"file1.txt": "content1", "file2.txt": "content2", "MANIFEST.json": "{}"})
+	expected := buildExpectedFilesMap(t, zipPath)
+	delete(expected, "MANIFEST.json")
+	reportPath := filepath.Join(tempDir, "output_validation.json")
+	valid, err := Run(context.Background(), zipPath, &repackage.Result{Files: expected, ManifestName: "MANIFEST.json"},
+		Options{SigningKey: signingKey})
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	t.Run("Returns error when the report cannot be read", func(t *testing.T) {
+		verification, err := Verify(context.Background(), zipPath, filepath.Join(tempDir, "missing.json"), publicKey)
+
+		assert.Error(t, err)
+		assert.Nil(t, verification)
+		assert.Contains(t, err.Error(), "failed to read validation report")
+	})
+
+	t.Run("Returns error when the report isn't signed", func(t *testing.T) {
+		unsignedReportPath := filepath.Join(tempDir, "unsigned.json")
+		content, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(unsignedReportPath, content, 0o644))
+
+		verification, err := Verify(context.Background(), zipPath, unsignedReportPath, publicKey)
+
+		assert.Error(t, err)
+		assert.Nil(t, verification)
+		assert.Contains(t, err.Error(), "failed to read validation report signature")
+	})
+
+	t.Run("Returns error when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		verification, err := Verify(ctx, zipPath, reportPath, publicKey)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, verification)
+	})
+
+	t.Run("Successfully rejects a signature made with another key", func(t *testing.T) {
+		verification, err := Verify(context.Background(), zipPath, reportPath, otherPublicKey)
+
+		assert.NoError(t, err)
+		assert.False(t, verification.Valid)
+		assert.False(t, verification.SignatureValid)
+		assert.True(t, verification.ReportValid)
+	})
+
+	t.Run("Successfully rejects a modified report", func(t *testing.T) {
+		content, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+		signature, err := os.ReadFile(SignaturePath(reportPath))
+		require.NoError(t, err)
+		modifiedReportPath := filepath.Join(tempDir, "modified.json")
+		require.NoError(t, os.WriteFile(modifiedReportPath, append(content, '\n'), 0o644))
+		require.NoError(t, os.WriteFile(SignaturePath(modifiedReportPath), signature, 0o644))
+
+		verification, err := Verify(context.Background(), zipPath, modifiedReportPath, publicKey)
+
+		assert.NoError(t, err)
+		assert.False(t, verification.Valid)
+		assert.False(t, verification.SignatureValid)
+	})
+
+	t.Run("Successfully reports modified, missing and unexpected entries", func(t *testing.T) {
+		tamperedZipPath := filepath.Join(tempDir, "tampered.zip")
+		makeTestZip(t, tamperedZipPath, map[string]string{"file1.txt": "tampered", "MANIFEST.json": "{}", "extra.txt": "extra"})
+
+		verification, err := Verify(context.Background(), tamperedZipPath, reportPath, publicKey)
+
+		assert.NoError(t, err)
+		assert.False(t, verification.Valid)
+		assert.True(t, verification.SignatureValid)
+		require.Len(t, verification.Files, 2)
+		assert.Equal(t, "MANIFEST.json", verification.Files[0].Name)
+		assert.True(t, verification.Files[0].Match)
+		assert.Equal(t, "file1.txt", verification.Files[1].Name)
+		assert.False(t, verification.Files[1].Match)
+		assert.Equal(t, []VerifyProblem{
+			{Name: "extra.txt", Kind: outputProblemUnexpected, Problem: "entry is not part of the repackaging result"},
+			{Name: "file2.txt", Kind: outputProblemMissing, Problem: "file listed by the report is missing from the output zip"},
+		}, verification.Problems)
+	})
+
+	t.Run("Successfully verifies a signed output", func(t *testing.T) {
+		verification, err := Verify(context.Background(), zipPath, reportPath, publicKey)
+
+		assert.NoError(t, err)
+		assert.True(t, verification.Valid)
+		assert.True(t, verification.SignatureValid)
+		assert.Equal(t, repackage.HashSHA256, verification.HashAlgorithm)
+		assert.Len(t, verification.Files, 3)
+		assert.Empty(t, verification.Problems)
+	})
+
+	// signWithAlgorithm signs the report of an output ZIP holding file1.txt whose checksum was
+	// computed with the algorithm, and returns the paths of the output ZIP and of the report.
+	signWithAlgorithm := func(t *testing.T, algorithm repackage.HashAlgorithm, content string) (string, string) {
+		algorithmDir := t.TempDir()
+		algorithmZipPath := filepath.Join(algorithmDir, "output.zip")
+		makeTestZip(t, algorithmZipPath, map[string]string{"file1.txt": content})
+		zipReader, err := zip.OpenReader(algorithmZipPath)
+		require.NoError(t, err)
+		hash, err := repackage.HashOf(zipReader.File[0], algorithm)
+		require.NoError(t, err)
+		require.NoError(t, zipReader.Close())
+		files := map[string]repackage.FileInfo{"file1.txt": {OriginalPath: "file1.txt", HashAlgorithm: algorithm, Hash: hash}}
+		_, err = Run(context.Background(), algorithmZipPath, &repackage.Result{Files: files},
+			Options{SigningKey: signingKey, RepackageOptions: repackage.Options{HashAlgorithm: algorithm}})
+		require.NoError(t, err)
+		return algorithmZipPath, filepath.Join(algorithmDir, "output_validation.json")
+	}
+
+	t.Run("Successfully rejects swapped entries with the same CRC-32C", func(t *testing.T) {
+		crcZipPath, crcReportPath := signWithAlgorithm(t, repackage.HashCRC32, "content 1371838")
+		makeTestZip(t, crcZipPath, map[string]string{"file1.txt": "content 2000402"})
+
+		verification, err := Verify(context.Background(), crcZipPath, crcReportPath, publicKey)
+
+		assert.NoError(t, err)
+		assert.False(t, verification.Valid)
+		assert.True(t, verification.SignatureValid)
+		assert.Equal(t, repackage.HashCRC32, verification.HashAlgorithm)
+		assert.Equal(t, []VerifiedFile{{Name: "file1.txt", Expected: "ae8e20d8", Actual: "ae8e20d8", Match: true}}, verification.Files,
+			"Both contents should have the same CRC-32C")
+		assert.Equal(t, []VerifyProblem{{
+			Name:    "output_validation.json",
+			Kind:    reportProblemWeakHash,
+			Problem: "crc32 checksums can be forged, so the signature does not cover the entries",
+		}}, verification.Problems)
+	})
+
+	t.Run("Successfully verifies the checksums of another algorithm", func(t *testing.T) {
+		sha512ZipPath, sha512ReportPath := signWithAlgorithm(t, repackage.HashSHA512, "content1")
+
+		verification, err := Verify(context.Background(), sha512ZipPath, sha512ReportPath, publicKey)
+
+		assert.NoError(t, err)
+		assert.True(t, verification.Valid)
+		assert.Equal(t, repackage.HashSHA512, verification.HashAlgorithm)
+		require.Len(t, verification.Files, 1)
+		assert.True(t, verification.Files[0].Match)
+		assert.Len(t, verification.Files[0].Actual, 128)
+		assert.Empty(t, verification.Problems)
+	})
+}
@@ -0,0 +1,7 @@
+// Package version exposes the version of rezip.
+package version
+
+// Version is the version of rezip. Release builds set it with:
+//
+//	go build -ldflags "-X github.com/yash15112001/rezip/internal/version.Version=v1.2.3" ./cmd/main.go
+var Version = "dev"
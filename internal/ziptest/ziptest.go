@@ -0,0 +1,34 @@
+// Package ziptest writes ZIP archives for the tests of other packages.
+package ziptest
+
+import (
+	"archive/zip"
+	"os"
+)
+
+// Write writes a ZIP archive holding the given name and content pairs, in the given order, so
+// that tests depending on the order of entries are deterministic. Names ending with a slash are
+// written as directories.
+func Write(path string, entries [][2]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	for _, entry := range entries {
+		writer, err := zipWriter.Create(entry[0])
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write([]byte(entry[1])); err != nil {
+			return err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}